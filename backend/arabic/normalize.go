@@ -0,0 +1,174 @@
+// Package arabic normalizes Arabic text from the ISX website so that
+// titles, tickers and search queries compare equal regardless of how the
+// alef, hamza, taa marbuta, tatweel, diacritics and digits were typed.
+package arabic
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Characters dropped entirely by Normalize
+const (
+	tatweel            = '\u0640'
+	superscriptAlef    = '\u0670'
+	zeroWidthNonJoiner = '\u200c'
+	zeroWidthJoiner    = '\u200d'
+	leftToRightMark    = '\u200e'
+	rightToLeftMark    = '\u200f'
+	byteOrderMark      = '\ufeff'
+)
+
+// Letter variants folded into a single form by Normalize
+var letterFolds = map[rune]rune{
+	'أ': 'ا',
+	'إ': 'ا',
+	'آ': 'ا',
+	'ٱ': 'ا',
+	'ى': 'ي',
+	'ة': 'ه',
+	'ؤ': 'و',
+	'ئ': 'ي',
+	'ک': 'ك', // Persian kaf
+	'ی': 'ي', // Persian yeh
+}
+
+// isDiacritic reports whether r is an Arabic harakat mark
+func isDiacritic(r rune) bool {
+	return (r >= '\u064b' && r <= '\u065f') || r == superscriptAlef
+}
+
+// isInvisible reports whether r is a formatting character with no glyph
+func isInvisible(r rune) bool {
+	switch r {
+	case tatweel, zeroWidthNonJoiner, zeroWidthJoiner, leftToRightMark, rightToLeftMark, byteOrderMark:
+		return true
+	}
+	return false
+}
+
+// Normalize folds text into the form used for comparison and search:
+// diacritics and tatweel removed, alef/hamza/yeh/taa marbuta variants
+// unified, digits converted to ASCII, Latin letters lower-cased and
+// whitespace collapsed. The result is not meant to be shown to users.
+func Normalize(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for _, r := range s {
+		if isDiacritic(r) || isInvisible(r) {
+			continue
+		}
+		if folded, ok := letterFolds[r]; ok {
+			r = folded
+		}
+		if d, ok := digitValue(r); ok {
+			r = '0' + d
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// Clean tidies text for storage and display without changing its
+// spelling: tatweel and invisible formatting characters are removed and
+// whitespace is collapsed
+func Clean(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if isInvisible(r) {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Highlight wraps the words of text whose normalized form starts with one
+// of the normalized query terms in start and end markers. The original
+// spelling of text is kept.
+func Highlight(text string, terms []string, start, end string) string {
+	normTerms := normalizeTerms(terms)
+	if len(normTerms) == 0 {
+		return text
+	}
+
+	words := strings.Fields(text)
+	for i, word := range words {
+		words[i] = highlightWord(word, normTerms, start, end)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// Snippet returns about size words of text around the first word matching
+// one of the query terms, highlighted like Highlight. ellipsis marks text
+// cut off at either end. Text without a match gives its first words.
+func Snippet(text string, terms []string, start, end, ellipsis string, size int) string {
+	normTerms := normalizeTerms(terms)
+	words := strings.Fields(text)
+	if size < 1 || len(words) == 0 {
+		return ""
+	}
+
+	first := 0
+	for i, word := range words {
+		if highlightWord(word, normTerms, start, end) != word {
+			first = i
+			break
+		}
+	}
+
+	// Show some context before the match, as FTS5 snippets do
+	from := first - size/4
+	if from < 0 {
+		from = 0
+	}
+	to := from + size
+	if to > len(words) {
+		to = len(words)
+		from = max(0, to-size)
+	}
+
+	window := make([]string, 0, to-from)
+	for _, word := range words[from:to] {
+		window = append(window, highlightWord(word, normTerms, start, end))
+	}
+	snippet := strings.Join(window, " ")
+	if from > 0 {
+		snippet = ellipsis + snippet
+	}
+	if to < len(words) {
+		snippet += ellipsis
+	}
+	return snippet
+}
+
+// normalizeTerms returns the non-empty normalized query terms
+func normalizeTerms(terms []string) []string {
+	var normTerms []string
+	for _, term := range terms {
+		if t := Normalize(term); t != "" {
+			normTerms = append(normTerms, t)
+		}
+	}
+	return normTerms
+}
+
+// highlightWord wraps word, leaving its punctuation outside, when its
+// normalized form starts with one of normTerms
+func highlightWord(word string, normTerms []string, start, end string) string {
+	core := strings.TrimFunc(word, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+	norm := Normalize(core)
+	if norm == "" {
+		return word
+	}
+	for _, t := range normTerms {
+		if strings.HasPrefix(norm, t) {
+			return strings.Replace(word, core, start+core+end, 1)
+		}
+	}
+	return word
+}
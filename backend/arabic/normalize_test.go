@@ -0,0 +1,105 @@
+package arabic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"أرباح", "ارباح"},
+		{"إعلان", "اعلان"},
+		{"آلية", "اليه"},
+		{"ٱلشركة", "الشركه"},
+		{"مُسَاهِمِين", "مساهمين"},                     // diacritics
+		{"الـــشركة", "الشركه"},                        // tatweel
+		{"مستوى", "مستوي"},                             // alef maksura
+		{"مؤسسة", "موسسه"},                             // hamza on waw
+		{"هيئة", "هييه"},                               // hamza on yeh
+		{"کردستانی", "كردستاني"},                       // Persian kaf and yeh
+		{"\u200fمصرف\u200c بغداد\ufeff", "مصرف بغداد"}, // invisible marks
+		{"١٥/٠٩/٢٠٢٤", "15/09/2024"},
+		{"۱۲۳", "123"},
+		{"  Cash   DIVIDEND ", "cash dividend"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"  اعلان   مصرف\nبغداد ", "اعلان مصرف بغداد"},
+		{"الـــشركة", "الشركة"},
+		{"\u200fأرباح\u200e", "أرباح"},
+		{"مُسَاهِم ١٠٪", "مُسَاهِم ١٠٪"}, // spelling, diacritics and digits kept
+	}
+	for _, tt := range tests {
+		if got := Clean(tt.in); got != tt.want {
+			t.Errorf("Clean(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		want  string
+	}{
+		{"توزيع أرباح نقدية", []string{"ارباح"}, "توزيع [أرباح] نقدية"},
+		{"اجتماع الهيئة العامة.", []string{"العامه"}, "اجتماع الهيئة [العامة]."},
+		{"Cash Dividends (2024)", []string{"divid", "2024"}, "Cash [Dividends] ([2024])"},
+		{"بدون تطابق", []string{"ارباح"}, "بدون تطابق"},
+		{"نص", nil, "نص"},
+	}
+	for _, tt := range tests {
+		if got := Highlight(tt.text, tt.terms, "[", "]"); got != tt.want {
+			t.Errorf("Highlight(%q, %q) = %q, want %q", tt.text, tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	// Words w0 ... w39 with the match at w20
+	words := make([]string, 40)
+	for i := range words {
+		words[i] = "w" + string(rune('a'+i%26))
+	}
+	words[20] = "الإفصاح"
+	text := strings.Join(words, " ")
+
+	got := Snippet(text, []string{"الافصاح"}, "[", "]", "…", 8)
+	want := "…" + strings.Join(words[18:20], " ") + " [الإفصاح] " + strings.Join(words[21:26], " ") + "…"
+	if got != want {
+		t.Errorf("Snippet = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		size  int
+		want  string
+	}{
+		{"match at start", "أرباح السنة المالية ٢٠٢٤ كاملة", []string{"ارباح"}, 3, "[أرباح] السنة المالية…"},
+		{"match at end", "قررت الهيئة العامة توزيع الأرباح", []string{"الارباح"}, 3, "…العامة توزيع [الأرباح]"},
+		{"no match", "نص بدون اي تطابق", []string{"ارباح"}, 2, "نص بدون…"},
+		{"short text", "توزيع أرباح", []string{"ارباح"}, 10, "توزيع [أرباح]"},
+		{"empty text", "", []string{"ارباح"}, 10, ""},
+	}
+	for _, tt := range tests {
+		if got := Snippet(tt.text, tt.terms, "[", "]", "…", tt.size); got != tt.want {
+			t.Errorf("%s: Snippet = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package arabic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Arabic number punctuation
+const (
+	arabicDecimalSeparator   = '٫'
+	arabicThousandsSeparator = '٬'
+	arabicPercentSign        = '٪'
+	arabicComma              = '،'
+)
+
+// digitValue returns the value of an ASCII, Arabic-Indic (٠-٩) or
+// Extended Arabic-Indic (۰-۹) digit
+func digitValue(r rune) (rune, bool) {
+	switch {
+	case r >= '0' && r <= '9':
+		return r - '0', true
+	case r >= '٠' && r <= '٩':
+		return r - '٠', true
+	case r >= '۰' && r <= '۹':
+		return r - '۰', true
+	}
+	return 0, false
+}
+
+// NormalizeDigits replaces Arabic-Indic digits with ASCII digits and the
+// Arabic decimal separator with a dot, leaving everything else unchanged
+func NormalizeDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if d, ok := digitValue(r); ok {
+			return '0' + d
+		}
+		if r == arabicDecimalSeparator {
+			return '.'
+		}
+		return r
+	}, s)
+}
+
+// cleanNumber prepares a number for strconv: digits normalized, thousands
+// separators, percent signs and spaces removed
+func cleanNumber(s string) string {
+	s = NormalizeDigits(strings.TrimSpace(s))
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ',', arabicThousandsSeparator, arabicComma, '%', arabicPercentSign, ' ', '\u00a0':
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// ParseInt parses an integer written with ASCII or Arabic-Indic digits,
+// with or without thousands separators
+func ParseInt(s string) (int64, error) {
+	n, err := strconv.ParseInt(cleanNumber(s), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return n, nil
+}
+
+// ParseFloat parses a decimal number written with ASCII or Arabic-Indic
+// digits. A trailing percent sign is dropped, not applied.
+func ParseFloat(s string) (float64, error) {
+	f, err := strconv.ParseFloat(cleanNumber(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return f, nil
+}
+
+// ParseDate parses a date in the given layout after normalizing its digits
+func ParseDate(layout, s string) (time.Time, error) {
+	return time.Parse(layout, strings.TrimSpace(NormalizeDigits(s)))
+}
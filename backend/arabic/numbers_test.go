@@ -0,0 +1,90 @@
+package arabic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeDigits(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"٠١٢٣٤٥٦٧٨٩", "0123456789"},
+		{"۰۱۲۳۴۵۶۷۸۹", "0123456789"},
+		{"١٢٫٥", "12.5"},
+		{"سعر ١٬٢٥٠", "سعر 1٬250"}, // only digits and the decimal separator change
+	}
+	for _, tt := range tests {
+		if got := NormalizeDigits(tt.in); got != tt.want {
+			t.Errorf("NormalizeDigits(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseInt(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"1250", 1250},
+		{"١٢٥٠", 1250},
+		{"1,250,000", 1250000},
+		{"١٬٢٥٠٬٠٠٠", 1250000},
+		{"١،٢٥٠", 1250},
+		{" 1 250 000 ", 1250000},
+		{"-15", -15},
+	}
+	for _, tt := range tests {
+		got, err := ParseInt(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseInt(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "abc", "12.5", "١٢٫٥"} {
+		if _, err := ParseInt(in); err == nil {
+			t.Errorf("ParseInt(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestParseFloat(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"12.5", 12.5},
+		{"١٢٫٥", 12.5},
+		{"1,250.75", 1250.75},
+		{"١٠٪", 10}, // the percent sign is dropped, not applied
+		{"10 %", 10},
+		{"0.125", 0.125},
+	}
+	for _, tt := range tests {
+		got, err := ParseFloat(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseFloat(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "ten", "1.2.3"} {
+		if _, err := ParseFloat(in); err == nil {
+			t.Errorf("ParseFloat(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	got, err := ParseDate("02/01/2006", " ١٥/٠٩/٢٠٢٤ ")
+	if err != nil {
+		t.Fatalf("ParseDate: %v", err)
+	}
+	if want := time.Date(2024, 9, 15, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("ParseDate = %v, want %v", got, want)
+	}
+
+	if _, err := ParseDate("02/01/2006", "٣٢/٠٩/٢٠٢٤"); err == nil {
+		t.Error("ParseDate accepted day 32")
+	}
+}
@@ -0,0 +1,435 @@
+// Package calendar knows when the Iraq Stock Exchange trades: its weekly
+// schedule and session hours, public holidays and shortened sessions. The
+// schedule ships as a JSON data file and admins can add or override single
+// days, which are kept in the database.
+package calendar
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	// Asia/Baghdad must load on images without a zoneinfo database
+	_ "time/tzdata"
+)
+
+// Layouts of calendar dates and session times
+const (
+	DateLayout = "2006-01-02"
+	TimeLayout = "15:04"
+)
+
+// Kinds of special days
+const (
+	KindHoliday = "holiday"  // no trading
+	KindHalfDay = "half_day" // trading closes early
+	KindOpen    = "open"     // trading on a day that is normally closed
+)
+
+// How far NextOpen and PreviousTradingDay search before giving up
+const maxSearchDays = 366
+
+//go:embed calendar.json
+var defaultCalendar []byte
+
+// ErrInvalidDay is returned when an admin edit is not a valid special day
+var ErrInvalidDay = errors.New("invalid calendar day")
+
+// Day is a date whose trading differs from the weekly schedule
+type Day struct {
+	Date  string `json:"date"`
+	Kind  string `json:"kind"`
+	Close string `json:"close,omitempty"` // closing time of a half day
+	Name  string `json:"name"`
+	// Source is "file" for days from the data file and "admin" for edits
+	Source string `json:"source"`
+}
+
+// Config is the contents of a calendar data file
+type Config struct {
+	Timezone string   `json:"timezone"`
+	Weekend  []string `json:"weekend"`
+	Open     string   `json:"open"`
+	Close    string   `json:"close"`
+	// CoveredUntil is the last date (YYYY-MM-DD) the file lists holidays
+	// for. Later days follow the weekly schedule alone.
+	CoveredUntil string `json:"covered_until"`
+	Days         []Day  `json:"days"`
+}
+
+// Calendar answers questions about ISX trading days and hours
+type Calendar struct {
+	db *sql.DB
+
+	loc     *time.Location
+	weekend map[time.Weekday]bool
+	open    time.Duration // session start, from midnight
+	close   time.Duration // session end, from midnight
+	config  Config
+
+	mu       sync.RWMutex
+	fileDays map[string]Day
+	days     map[string]Day // file days with admin edits applied
+	warnedOn string         // last date a coverage warning was logged
+}
+
+// LoadFromEnv loads the calendar file named by MARKET_CALENDAR_FILE, or the
+// built-in calendar, and applies the admin edits stored in db
+func LoadFromEnv(db *sql.DB) (*Calendar, error) {
+	data := defaultCalendar
+	if path := os.Getenv("MARKET_CALENDAR_FILE"); path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("error reading market calendar: %w", err)
+		}
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("error parsing market calendar: %w", err)
+	}
+
+	c, err := New(cfg, db)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.loadEdits(); err != nil {
+		return nil, err
+	}
+	c.CheckCoverage(time.Now())
+	return c, nil
+}
+
+// New builds a calendar from cfg. Admin edits are saved to db when it is
+// not nil.
+func New(cfg Config, db *sql.DB) (*Calendar, error) {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid calendar timezone %q: %w", cfg.Timezone, err)
+	}
+
+	weekend := make(map[time.Weekday]bool)
+	for _, name := range cfg.Weekend {
+		day, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekend day %q", name)
+		}
+		weekend[day] = true
+	}
+
+	openAt, err := parseClock(cfg.Open)
+	if err != nil {
+		return nil, fmt.Errorf("invalid opening time: %w", err)
+	}
+	closeAt, err := parseClock(cfg.Close)
+	if err != nil {
+		return nil, fmt.Errorf("invalid closing time: %w", err)
+	}
+	if closeAt <= openAt {
+		return nil, fmt.Errorf("closing time %s is not after opening time %s", cfg.Close, cfg.Open)
+	}
+	if cfg.CoveredUntil != "" {
+		if _, err := time.Parse(DateLayout, cfg.CoveredUntil); err != nil {
+			return nil, fmt.Errorf("covered_until %q is not in YYYY-MM-DD format", cfg.CoveredUntil)
+		}
+	}
+
+	c := &Calendar{
+		db:       db,
+		loc:      loc,
+		weekend:  weekend,
+		open:     openAt,
+		close:    closeAt,
+		config:   cfg,
+		fileDays: make(map[string]Day),
+		days:     make(map[string]Day),
+	}
+	for _, day := range cfg.Days {
+		day.Source = "file"
+		if err := c.validate(&day); err != nil {
+			return nil, err
+		}
+		c.fileDays[day.Date] = day
+		c.days[day.Date] = day
+	}
+
+	return c, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// parseClock parses an HH:MM time of day into an offset from midnight
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse(TimeLayout, s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not in HH:MM format", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// validate checks a special day and normalizes its fields
+func (c *Calendar) validate(day *Day) error {
+	if _, err := time.Parse(DateLayout, day.Date); err != nil {
+		return fmt.Errorf("%w: date %q is not in YYYY-MM-DD format", ErrInvalidDay, day.Date)
+	}
+	switch day.Kind {
+	case KindHoliday, KindOpen:
+		day.Close = ""
+	case KindHalfDay:
+		closeAt, err := parseClock(day.Close)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidDay, err)
+		}
+		if closeAt <= c.open || closeAt >= c.close {
+			return fmt.Errorf("%w: half day on %s must close between %s and %s",
+				ErrInvalidDay, day.Date, c.config.Open, c.config.Close)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q on %s", ErrInvalidDay, day.Kind, day.Date)
+	}
+	return nil
+}
+
+// Location returns the exchange's time zone
+func (c *Calendar) Location() *time.Location {
+	return c.loc
+}
+
+// Config returns the weekly schedule the calendar was built from
+func (c *Calendar) Config() Config {
+	return c.config
+}
+
+// Covers reports whether the data file lists the holidays of t's day. A
+// calendar without covered_until covers no day.
+func (c *Calendar) Covers(t time.Time) bool {
+	return c.config.CoveredUntil != "" && c.midnight(t).Format(DateLayout) <= c.config.CoveredUntil
+}
+
+// CheckCoverage is Covers that also logs a warning, at most once a day,
+// when t is not covered, since holidays would then be taken for trading
+// days
+func (c *Calendar) CheckCoverage(t time.Time) bool {
+	if c.Covers(t) {
+		return true
+	}
+
+	date := c.midnight(t).Format(DateLayout)
+	c.mu.Lock()
+	warned := c.warnedOn == date
+	c.warnedOn = date
+	c.mu.Unlock()
+	if !warned {
+		if c.config.CoveredUntil == "" {
+			log.Printf("Warning: the market calendar does not say until when it lists holidays, update it and set covered_until")
+		} else {
+			log.Printf("Warning: the market calendar lists holidays only until %s, update it or add holidays as admin edits", c.config.CoveredUntil)
+		}
+	}
+	return false
+}
+
+// midnight returns the start of t's day in the exchange's time zone
+func (c *Calendar) midnight(t time.Time) time.Time {
+	t = t.In(c.loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, c.loc)
+}
+
+// session returns the trading hours of the day starting at midnight.
+// ok is false if the exchange does not trade that day.
+func (c *Calendar) session(midnight time.Time) (open, close time.Time, ok bool) {
+	c.mu.RLock()
+	day, special := c.days[midnight.Format(DateLayout)]
+	c.mu.RUnlock()
+
+	trading := !c.weekend[midnight.Weekday()]
+	closeAt := c.close
+	if special {
+		switch day.Kind {
+		case KindHoliday:
+			trading = false
+		case KindOpen:
+			trading = true
+		case KindHalfDay:
+			trading = true
+			closeAt, _ = parseClock(day.Close)
+		}
+	}
+	if !trading {
+		return time.Time{}, time.Time{}, false
+	}
+
+	return midnight.Add(c.open), midnight.Add(closeAt), true
+}
+
+// Session returns the trading hours of t's day
+func (c *Calendar) Session(t time.Time) (open, close time.Time, ok bool) {
+	return c.session(c.midnight(t))
+}
+
+// IsTradingDay reports whether the exchange trades at all on t's day
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	_, _, ok := c.Session(t)
+	return ok
+}
+
+// IsOpen reports whether the exchange is in session at t
+func (c *Calendar) IsOpen(t time.Time) bool {
+	open, close, ok := c.Session(t)
+	return ok && !t.Before(open) && t.Before(close)
+}
+
+// NextOpen returns the start of the first session that begins after t
+func (c *Calendar) NextOpen(t time.Time) time.Time {
+	day := c.midnight(t)
+	for i := 0; i <= maxSearchDays; i++ {
+		if open, _, ok := c.session(day); ok && open.After(t) {
+			return open
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}
+}
+
+// NextClose returns the end of the session in progress at t, or of the next
+// session if the exchange is closed
+func (c *Calendar) NextClose(t time.Time) time.Time {
+	if open, close, ok := c.Session(t); ok && !t.Before(open) && t.Before(close) {
+		return close
+	}
+	_, close, _ := c.Session(c.NextOpen(t))
+	return close
+}
+
+// PreviousTradingDay returns midnight of the last trading day before t's day
+func (c *Calendar) PreviousTradingDay(t time.Time) time.Time {
+	day := c.midnight(t)
+	for i := 0; i < maxSearchDays; i++ {
+		day = day.AddDate(0, 0, -1)
+		if _, _, ok := c.session(day); ok {
+			return day
+		}
+	}
+	return time.Time{}
+}
+
+// Days lists the special days between from and to, inclusive
+func (c *Calendar) Days(from, to time.Time) []Day {
+	first := c.midnight(from).Format(DateLayout)
+	last := c.midnight(to).Format(DateLayout)
+
+	c.mu.RLock()
+	days := []Day{}
+	for date, day := range c.days {
+		if date >= first && date <= last {
+			days = append(days, day)
+		}
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	return days
+}
+
+// loadEdits applies the admin edits stored in the database
+func (c *Calendar) loadEdits() error {
+	if c.db == nil {
+		return nil
+	}
+
+	rows, err := c.db.Query("SELECT date, kind, close_time, name FROM market_calendar_days")
+	if err != nil {
+		return fmt.Errorf("error querying calendar edits: %w", err)
+	}
+	defer rows.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for rows.Next() {
+		day := Day{Source: "admin"}
+		if err := rows.Scan(&day.Date, &day.Kind, &day.Close, &day.Name); err != nil {
+			return fmt.Errorf("error scanning calendar edit: %w", err)
+		}
+		if err := c.validate(&day); err != nil {
+			log.Printf("Ignoring stored calendar edit: %v", err)
+			continue
+		}
+		c.days[day.Date] = day
+	}
+
+	return rows.Err()
+}
+
+// SetDay adds or replaces a special day on behalf of an admin
+func (c *Calendar) SetDay(day Day, updatedBy string) (Day, error) {
+	day.Source = "admin"
+	if err := c.validate(&day); err != nil {
+		return day, err
+	}
+
+	if c.db != nil {
+		if _, err := c.db.Exec(`
+			INSERT INTO market_calendar_days (date, kind, close_time, name, updated_by)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(date) DO UPDATE SET
+				kind = excluded.kind,
+				close_time = excluded.close_time,
+				name = excluded.name,
+				updated_by = excluded.updated_by,
+				updated_at = CURRENT_TIMESTAMP`,
+			day.Date, day.Kind, day.Close, day.Name, updatedBy); err != nil {
+			return day, fmt.Errorf("error saving calendar day: %w", err)
+		}
+	}
+
+	c.mu.Lock()
+	c.days[day.Date] = day
+	c.mu.Unlock()
+
+	log.Printf("Calendar day %s set to %s by %s", day.Date, day.Kind, updatedBy)
+	return day, nil
+}
+
+// DeleteDay removes an admin edit, restoring the data file's entry for
+// that date if it has one. It reports whether there was an edit to remove.
+func (c *Calendar) DeleteDay(date string) (bool, error) {
+	c.mu.RLock()
+	day, ok := c.days[date]
+	c.mu.RUnlock()
+	if !ok || day.Source != "admin" {
+		return false, nil
+	}
+
+	if c.db != nil {
+		if _, err := c.db.Exec("DELETE FROM market_calendar_days WHERE date = ?", date); err != nil {
+			return false, fmt.Errorf("error deleting calendar day: %w", err)
+		}
+	}
+
+	c.mu.Lock()
+	if fileDay, ok := c.fileDays[date]; ok {
+		c.days[date] = fileDay
+	} else {
+		delete(c.days, date)
+	}
+	c.mu.Unlock()
+
+	log.Printf("Calendar edit for %s removed", date)
+	return true, nil
+}
@@ -0,0 +1,121 @@
+// Package classifier tags ISX disclosures with a category using keyword
+// rules kept in a JSON data file.
+package classifier
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"isxportfolio-backend/arabic"
+	"log"
+	"os"
+	"strings"
+)
+
+// Other is the category of items that match no rule
+const Other = "other"
+
+//go:embed rules.json
+var defaultRules []byte
+
+// Category is a disclosure category and the keywords that identify it
+type Category struct {
+	ID       string   `json:"id"`
+	NameAr   string   `json:"name_ar"`
+	NameEn   string   `json:"name_en"`
+	Keywords []string `json:"keywords"`
+
+	normalized []string
+}
+
+// Classifier assigns categories to news titles. Categories are tried in the
+// order they appear in the rules file and the first match wins.
+type Classifier struct {
+	categories []Category
+}
+
+// Load reads the rules from path, or the built-in rules when path is empty
+func Load(path string) (*Classifier, error) {
+	data := defaultRules
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("error reading category rules: %w", err)
+		}
+	}
+
+	var rules struct {
+		Categories []Category `json:"categories"`
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("error parsing category rules: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range rules.Categories {
+		cat := &rules.Categories[i]
+		if cat.ID == "" || cat.ID == Other || seen[cat.ID] {
+			return nil, fmt.Errorf("invalid or duplicate category id %q", cat.ID)
+		}
+		seen[cat.ID] = true
+
+		for _, kw := range cat.Keywords {
+			if n := arabic.Normalize(kw); n != "" {
+				cat.normalized = append(cat.normalized, n)
+			}
+		}
+	}
+
+	return &Classifier{categories: rules.Categories}, nil
+}
+
+// LoadFromEnv loads the rules file named by NEWS_CATEGORY_RULES, falling
+// back to the built-in rules
+func LoadFromEnv() *Classifier {
+	path := os.Getenv("NEWS_CATEGORY_RULES")
+	c, err := Load(path)
+	if err != nil && path != "" {
+		log.Printf("Error loading category rules from %s, using built-in rules: %v", path, err)
+		c, err = Load("")
+	}
+	if err != nil {
+		log.Fatalf("Built-in category rules are invalid: %v", err)
+	}
+	return c
+}
+
+// Classify returns the category ID for a news title
+func (c *Classifier) Classify(title string) string {
+	norm := arabic.Normalize(title)
+	if norm == "" {
+		return Other
+	}
+
+	for _, cat := range c.categories {
+		for _, kw := range cat.normalized {
+			if strings.Contains(norm, kw) {
+				return cat.ID
+			}
+		}
+	}
+
+	return Other
+}
+
+// Categories lists the known categories, not including Other
+func (c *Classifier) Categories() []Category {
+	return c.categories
+}
+
+// IsCategory reports whether id is a known category or Other
+func (c *Classifier) IsCategory(id string) bool {
+	if id == Other {
+		return true
+	}
+	for _, cat := range c.categories {
+		if cat.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package classifier
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	c, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"إيقاف التداول على أسهم شركة بغداد للمشروبات الغازية", "trading_suspension"},
+		{"ايقاف التداول على اسهم مصرف الشرق الاوسط", "trading_suspension"},
+		// Resumption notices mention the suspension too, so they come first
+		{"استئناف التداول على اسهم الشركة بعد إيقاف التداول", "trading_resumption"},
+		{"توزيع الأرباح النقدية لمصرف بغداد", "dividend"},
+		{"توزيع الارباح النقديه", "dividend"}, // hamza and taa marbuta typed plainly
+		{"Cash Dividend Distribution - BBOB", "dividend"},
+		// Dividend comes before the meeting that approved it
+		{"قرارات اجتماع الهيئة العامة بتوزيع أرباح نقدية", "dividend"},
+		{"دعوة الهيئة العامة للشركة للاجتماع", "agm_invitation"},
+		{"زيادة راس المال عن طريق الاكتتاب", "capital_increase"},
+		{"تجزئة الأسهم بنسبة 2 الى 1", "capital_increase"},
+		{"Stock split of Asia Cell", "capital_increase"},
+		{"البيانات المالية السنوية لعام 2023", "annual_financials"},
+		{"البيانات المالية للفصل الثالث", "quarterly_financials"},
+		{"انتخاب مجلس الإدارة الجديد", "board_change"},
+		{"جلسة تداول اعتيادية", Other},
+		{"", Other},
+	}
+	for _, tt := range tests {
+		if got := c.Classify(tt.title); got != tt.want {
+			t.Errorf("Classify(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestIsCategory(t *testing.T) {
+	c, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for _, id := range []string{"dividend", "board_change", Other} {
+		if !c.IsCategory(id) {
+			t.Errorf("IsCategory(%q) = false", id)
+		}
+	}
+	if c.IsCategory("merger") {
+		t.Error(`IsCategory("merger") = true`)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	c, err := Load(write("rules.json", `{"categories": [{"id": "merger", "keywords": ["اندماج", "merger"]}]}`))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := c.Classify("اندماج المصرفين"); got != "merger" {
+		t.Errorf("Classify = %q, want merger", got)
+	}
+	if got := c.Classify("توزيع أرباح"); got != Other {
+		t.Errorf("Classify = %q, want the built-in rules to be replaced", got)
+	}
+
+	for name, data := range map[string]string{
+		"missing id":   `{"categories": [{"keywords": ["x"]}]}`,
+		"reserved id":  `{"categories": [{"id": "other", "keywords": ["x"]}]}`,
+		"duplicate id": `{"categories": [{"id": "a", "keywords": ["x"]}, {"id": "a", "keywords": ["y"]}]}`,
+		"invalid json": `{"categories": [`,
+	} {
+		if _, err := Load(write("bad.json", data)); err == nil {
+			t.Errorf("%s: Load succeeded, want an error", name)
+		}
+	}
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
@@ -22,7 +22,8 @@ func InitDB() {
 	log.Printf("Initializing database at: %s", dbPath)
 
 	var err error
-	DB, err = sql.Open("sqlite3", dbPath)
+	// Wait on locks instead of failing when the scraper and API write at once
+	DB, err = sql.Open("sqlite3", dbPath+"?_busy_timeout=5000&_foreign_keys=on&_journal_mode=WAL")
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
@@ -57,9 +58,66 @@ func InitDB() {
 	}
 	log.Printf("Verified users table exists: %s", tableName)
 
+	// Create market data tables
+	for _, table := range marketTables {
+		if _, err := DB.Exec(table.ddl); err != nil {
+			log.Fatalf("Failed to create %s table: %v", table.name, err)
+		}
+		log.Printf("%s table created/verified successfully", table.name)
+	}
+
+	// Add columns introduced after the tables were first created
+	for _, col := range marketColumns {
+		if err := ensureColumn(col.table, col.column, col.definition); err != nil {
+			log.Fatalf("Failed to add %s.%s column: %v", col.table, col.column, err)
+		}
+	}
+	for _, ddl := range marketIndexes {
+		if _, err := DB.Exec(ddl); err != nil {
+			log.Fatalf("Failed to create index: %v", err)
+		}
+	}
+
+	// Create optional tables that depend on SQLite build features
+	for _, table := range optionalMarketTables {
+		if _, err := DB.Exec(table.ddl); err != nil {
+			log.Printf("Warning: %s table not available: %v", table.name, err)
+			continue
+		}
+		log.Printf("%s table created/verified successfully", table.name)
+	}
+
 	log.Println("Database initialized successfully")
 }
 
+// ensureColumn adds a column to an existing table if it is missing
+func ensureColumn(table, column, definition string) error {
+	rows, err := DB.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = DB.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+	if err == nil {
+		log.Printf("Added column %s.%s", table, column)
+	}
+	return err
+}
+
 func TestDatabaseWrite() {
 	_, err := DB.Exec(`
 		INSERT INTO users (email, name) 
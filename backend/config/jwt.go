@@ -1,6 +1,8 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"time"
@@ -32,3 +34,26 @@ func GenerateJWTToken(email string) string {
 
 	return tokenString
 }
+
+// ParseJWTToken validates a token issued at login and returns its email
+func ParseJWTToken(tokenString string) (string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return jwtSecret, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return "", errors.New("invalid token claims")
+	}
+	email, _ := claims["email"].(string)
+	if email == "" {
+		return "", errors.New("token has no email")
+	}
+	return email, nil
+}
@@ -0,0 +1,226 @@
+package config
+
+// Tables used by the market data features, created by InitDB in order
+var marketTables = []struct {
+	name string
+	ddl  string
+}{
+	{"news_items", `
+	CREATE TABLE IF NOT EXISTS news_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		link TEXT NOT NULL UNIQUE,
+		title TEXT NOT NULL,
+		date TEXT NOT NULL DEFAULT '',
+		published_at DATETIME,
+		ticker TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_news_items_published_at ON news_items(published_at);
+	CREATE INDEX IF NOT EXISTS idx_news_items_ticker ON news_items(ticker);`},
+	{"news_attachments", `
+	CREATE TABLE IF NOT EXISTS news_attachments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		news_id INTEGER NOT NULL REFERENCES news_items(id) ON DELETE CASCADE,
+		url TEXT NOT NULL,
+		filename TEXT NOT NULL,
+		is_loaded INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(news_id, url)
+	);`},
+	{"news_tickers", `
+	CREATE TABLE IF NOT EXISTS news_tickers (
+		news_id INTEGER NOT NULL REFERENCES news_items(id) ON DELETE CASCADE,
+		ticker TEXT NOT NULL,
+		position INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (news_id, ticker)
+	);
+	CREATE INDEX IF NOT EXISTS idx_news_tickers_ticker ON news_tickers(ticker);`},
+	{"news_backfill_checkpoints", `
+	CREATE TABLE IF NOT EXISTS news_backfill_checkpoints (
+		list_url TEXT PRIMARY KEY,
+		next_url TEXT NOT NULL DEFAULT '',
+		until_date TEXT NOT NULL,
+		pages INTEGER NOT NULL DEFAULT 0,
+		oldest_date DATETIME,
+		done INTEGER NOT NULL DEFAULT 0,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`},
+	{"market_calendar_days", `
+	CREATE TABLE IF NOT EXISTS market_calendar_days (
+		date TEXT PRIMARY KEY,
+		kind TEXT NOT NULL,
+		close_time TEXT NOT NULL DEFAULT '',
+		name TEXT NOT NULL DEFAULT '',
+		updated_by TEXT NOT NULL DEFAULT '',
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`},
+	{"job_runs", `
+	CREATE TABLE IF NOT EXISTS job_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		job_name TEXT NOT NULL,
+		trigger TEXT NOT NULL,
+		status TEXT NOT NULL,
+		started_at DATETIME NOT NULL,
+		finished_at DATETIME,
+		duration_ms INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX IF NOT EXISTS idx_job_runs_job_name ON job_runs(job_name, id);`},
+	{"scrape_runs", `
+	CREATE TABLE IF NOT EXISTS scrape_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		run_id TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		status TEXT NOT NULL,
+		started_at DATETIME NOT NULL,
+		duration_ms INTEGER NOT NULL DEFAULT 0,
+		list_pages INTEGER NOT NULL DEFAULT 0,
+		empty_list_pages INTEGER NOT NULL DEFAULT 0,
+		list_errors INTEGER NOT NULL DEFAULT 0,
+		row_count INTEGER NOT NULL DEFAULT 0,
+		item_count INTEGER NOT NULL DEFAULT 0,
+		new_items INTEGER NOT NULL DEFAULT 0,
+		detail_pages INTEGER NOT NULL DEFAULT 0,
+		detail_errors INTEGER NOT NULL DEFAULT 0,
+		missing_date INTEGER NOT NULL DEFAULT 0,
+		missing_title INTEGER NOT NULL DEFAULT 0,
+		missing_link INTEGER NOT NULL DEFAULT 0,
+		missing_ticker INTEGER NOT NULL DEFAULT 0,
+		degraded INTEGER NOT NULL DEFAULT 0,
+		degraded_reasons TEXT NOT NULL DEFAULT '',
+		error TEXT NOT NULL DEFAULT ''
+	);`},
+	{"companies", `
+	CREATE TABLE IF NOT EXISTS companies (
+		ticker TEXT PRIMARY KEY,
+		name_ar TEXT NOT NULL DEFAULT '',
+		name_en TEXT NOT NULL DEFAULT '',
+		sector TEXT NOT NULL DEFAULT '',
+		listing_date TEXT NOT NULL DEFAULT '',
+		paid_up_capital INTEGER NOT NULL DEFAULT 0,
+		shares_outstanding INTEGER NOT NULL DEFAULT 0,
+		status TEXT NOT NULL DEFAULT 'listed',
+		profile_updated_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`},
+	{"price_history", `
+	CREATE TABLE IF NOT EXISTS price_history (
+		ticker TEXT NOT NULL,
+		date TEXT NOT NULL,
+		open REAL NOT NULL,
+		high REAL NOT NULL,
+		low REAL NOT NULL,
+		close REAL NOT NULL,
+		volume INTEGER NOT NULL DEFAULT 0,
+		value REAL NOT NULL DEFAULT 0,
+		trades INTEGER NOT NULL DEFAULT 0,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (ticker, date)
+	);
+	CREATE INDEX IF NOT EXISTS idx_price_history_date ON price_history(date);`},
+	{"quotes", `
+	CREATE TABLE IF NOT EXISTS quotes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		ticker TEXT NOT NULL,
+		as_of DATETIME NOT NULL,
+		last REAL NOT NULL,
+		change REAL NOT NULL DEFAULT 0,
+		change_percent REAL NOT NULL DEFAULT 0,
+		bid REAL NOT NULL DEFAULT 0,
+		ask REAL NOT NULL DEFAULT 0,
+		volume INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS idx_quotes_ticker_as_of ON quotes(ticker, as_of);
+	CREATE INDEX IF NOT EXISTS idx_quotes_as_of ON quotes(as_of);`},
+	{"corporate_actions", `
+	CREATE TABLE IF NOT EXISTS corporate_actions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		ticker TEXT NOT NULL,
+		type TEXT NOT NULL,
+		ratio REAL NOT NULL DEFAULT 0,
+		amount_per_share REAL NOT NULL DEFAULT 0,
+		announced_date TEXT NOT NULL DEFAULT '',
+		ex_date TEXT NOT NULL DEFAULT '',
+		record_date TEXT NOT NULL DEFAULT '',
+		payment_date TEXT NOT NULL DEFAULT '',
+		news_id INTEGER REFERENCES news_items(id) ON DELETE SET NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		source TEXT NOT NULL DEFAULT 'extracted',
+		notes TEXT NOT NULL DEFAULT '',
+		reviewed_by TEXT NOT NULL DEFAULT '',
+		reviewed_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (news_id, ticker, type)
+	);
+	CREATE INDEX IF NOT EXISTS idx_corporate_actions_ticker ON corporate_actions(ticker, status);
+	CREATE INDEX IF NOT EXISTS idx_corporate_actions_status ON corporate_actions(status);`},
+	{"price_adjustments", `
+	CREATE TABLE IF NOT EXISTS price_adjustments (
+		ticker TEXT NOT NULL,
+		action_id INTEGER NOT NULL REFERENCES corporate_actions(id) ON DELETE CASCADE,
+		ex_date TEXT NOT NULL,
+		factor REAL NOT NULL,
+		PRIMARY KEY (ticker, action_id)
+	);`},
+	{"index_history", `
+	CREATE TABLE IF NOT EXISTS index_history (
+		code TEXT NOT NULL,
+		source TEXT NOT NULL,
+		date TEXT NOT NULL,
+		value REAL NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (code, source, date)
+	);`},
+	{"job_settings", `
+	CREATE TABLE IF NOT EXISTS job_settings (
+		name TEXT PRIMARY KEY,
+		paused INTEGER NOT NULL DEFAULT 0,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`},
+}
+
+// Columns added to market tables after their first release. InitDB adds
+// any that are missing from an existing database.
+var marketColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"news_attachments", "text", "TEXT NOT NULL DEFAULT ''"},
+	{"news_attachments", "text_extracted", "INTEGER NOT NULL DEFAULT 0"},
+	{"news_attachments", "sha256", "TEXT NOT NULL DEFAULT ''"},
+	{"news_attachments", "size", "INTEGER NOT NULL DEFAULT 0"},
+	{"news_items", "title_normalized", "TEXT NOT NULL DEFAULT ''"},
+	{"news_items", "category", "TEXT NOT NULL DEFAULT 'other'"},
+	{"news_items", "lang", "TEXT NOT NULL DEFAULT 'ar'"},
+	{"news_items", "story_id", "TEXT NOT NULL DEFAULT ''"},
+	{"news_items", "twin_id", "INTEGER REFERENCES news_items(id) ON DELETE SET NULL"},
+	{"news_items", "actions_scanned", "INTEGER NOT NULL DEFAULT 0"},
+	{"news_tickers", "verified", "INTEGER NOT NULL DEFAULT 1"},
+}
+
+// Indexes on columns from marketColumns, created once the columns exist
+var marketIndexes = []string{
+	"CREATE INDEX IF NOT EXISTS idx_news_items_story_id ON news_items(story_id)",
+	"CREATE INDEX IF NOT EXISTS idx_news_items_lang ON news_items(lang, twin_id)",
+	"CREATE INDEX IF NOT EXISTS idx_news_attachments_filename ON news_attachments(filename)",
+}
+
+// Tables that need optional SQLite features. The full-text index requires
+// a build with -tags sqlite_fts5; search is disabled when it is missing.
+var optionalMarketTables = []struct {
+	name string
+	ddl  string
+}{
+	{"news_search", `
+	CREATE VIRTUAL TABLE IF NOT EXISTS news_search USING fts5(
+		title,
+		body,
+		news_id UNINDEXED,
+		attachment_id UNINDEXED,
+		tokenize = 'unicode61 remove_diacritics 2'
+	);`},
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"isxportfolio-backend/market"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ActionHandler struct {
+	store *market.Store
+}
+
+// Constructor for the corporate action handler
+func NewActionHandler(store *market.Store) *ActionHandler {
+	return &ActionHandler{store: store}
+}
+
+// GetTickerActions handles GET /api/market/tickers/:ticker/actions?type=
+// It returns the ticker's approved corporate actions, most recent first,
+// and the price adjustment factors derived from them
+func (h *ActionHandler) GetTickerActions(c *gin.Context) {
+	filter := market.ActionFilter{
+		Ticker: strings.ToUpper(c.Param("ticker")),
+		Type:   c.Query("type"),
+		Status: market.ActionApproved,
+	}
+	if filter.Type != "" && !market.ValidActionType(filter.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": market.ErrInvalidActionType.Error()})
+		return
+	}
+
+	actions, err := h.store.Actions(filter)
+	if err != nil {
+		log.Printf("Error listing corporate actions of %s: %v", filter.Ticker, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch corporate actions"})
+		return
+	}
+	adjustments, err := h.store.Adjustments(filter.Ticker)
+	if err != nil {
+		log.Printf("Error listing price adjustments of %s: %v", filter.Ticker, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch corporate actions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ticker": filter.Ticker, "actions": actions, "adjustments": adjustments})
+}
+
+// ListActions handles GET /api/admin/actions?status=&ticker=
+// status defaults to pending, which lists the review queue
+func (h *ActionHandler) ListActions(c *gin.Context) {
+	filter := market.ActionFilter{
+		Ticker: c.Query("ticker"),
+		Status: c.DefaultQuery("status", market.ActionPending),
+	}
+	switch filter.Status {
+	case market.ActionPending, market.ActionApproved, market.ActionRejected:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, approved or rejected"})
+		return
+	}
+
+	actions, err := h.store.Actions(filter)
+	if err != nil {
+		log.Printf("Error listing corporate actions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch corporate actions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"actions": actions, "total": len(actions)})
+}
+
+// CreateAction handles POST /api/admin/actions
+// Actions entered by an admin are approved right away
+func (h *ActionHandler) CreateAction(c *gin.Context) {
+	var action market.CorporateAction
+	if err := json.NewDecoder(c.Request.Body).Decode(&action); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	action.Ticker = strings.ToUpper(strings.TrimSpace(action.Ticker))
+	if err := action.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	action.Source = market.SourceManual
+	action.Status = market.ActionPending
+
+	added, err := h.store.AddAction(&action)
+	if err != nil {
+		log.Printf("Error adding corporate action: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add corporate action"})
+		return
+	}
+	if !added {
+		c.JSON(http.StatusConflict, gin.H{"error": "This action is already recorded for the news item"})
+		return
+	}
+	h.review(c, action.ID, market.ActionApproved, http.StatusCreated)
+}
+
+// UpdateAction handles PUT /api/admin/actions/:id
+// Fields missing from the body keep their stored values
+func (h *ActionHandler) UpdateAction(c *gin.Context) {
+	action, ok := h.findAction(c)
+	if !ok {
+		return
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(action); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	action.ID, _ = strconv.ParseInt(c.Param("id"), 10, 64)
+	action.Ticker = strings.ToUpper(strings.TrimSpace(action.Ticker))
+	if err := action.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.UpdateAction(action); err != nil {
+		log.Printf("Error updating corporate action %d: %v", action.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update corporate action"})
+		return
+	}
+	log.Printf("Corporate action %d edited by %s", action.ID, c.GetString("email"))
+
+	updated, err := h.store.Action(action.ID)
+	if err != nil {
+		log.Printf("Error fetching corporate action %d: %v", action.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch corporate action"})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// ApproveAction handles POST /api/admin/actions/:id/approve
+func (h *ActionHandler) ApproveAction(c *gin.Context) {
+	action, ok := h.findAction(c)
+	if !ok {
+		return
+	}
+	// Extracted actions may lack a figure until an admin fills it in
+	if err := action.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.review(c, action.ID, market.ActionApproved, http.StatusOK)
+}
+
+// RejectAction handles POST /api/admin/actions/:id/reject
+func (h *ActionHandler) RejectAction(c *gin.Context) {
+	action, ok := h.findAction(c)
+	if !ok {
+		return
+	}
+	h.review(c, action.ID, market.ActionRejected, http.StatusOK)
+}
+
+// findAction loads the action named by the id parameter, writing an error
+// response and returning false if there is none
+func (h *ActionHandler) findAction(c *gin.Context) (*market.CorporateAction, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid action id"})
+		return nil, false
+	}
+	action, err := h.store.Action(id)
+	if errors.Is(err, market.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Corporate action not found"})
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error fetching corporate action %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch corporate action"})
+		return nil, false
+	}
+	return action, true
+}
+
+// review records the admin's decision on an action and responds with it
+func (h *ActionHandler) review(c *gin.Context, id int64, status string, code int) {
+	reviewer := c.GetString("email")
+	if err := h.store.ReviewAction(id, status, reviewer); err != nil {
+		log.Printf("Error reviewing corporate action %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review corporate action"})
+		return
+	}
+	log.Printf("Corporate action %d %s by %s", id, status, reviewer)
+
+	action, err := h.store.Action(id)
+	if err != nil {
+		log.Printf("Error fetching corporate action %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch corporate action"})
+		return
+	}
+	c.JSON(code, action)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"isxportfolio-backend/config"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin lets a request through only if it carries a valid token for
+// one of the emails listed in ADMIN_EMAILS. The admin's email is stored in
+// the context under "email".
+func RequireAdmin() gin.HandlerFunc {
+	admins := make(map[string]bool)
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			admins[email] = true
+		}
+	}
+
+	return func(c *gin.Context) {
+		tokenString, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || tokenString == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "No token provided"})
+			return
+		}
+
+		email, err := config.ParseJWTToken(tokenString)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		if !admins[strings.ToLower(email)] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			return
+		}
+
+		c.Set("email", email)
+		c.Next()
+	}
+}
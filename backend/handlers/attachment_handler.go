@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"isxportfolio-backend/scraper"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// How long clients may cache an attachment. Files never change once
+// published, and the ETag catches the rare re-download.
+const attachmentMaxAge = "public, max-age=86400"
+
+type AttachmentHandler struct {
+	store   *scraper.NewsStore
+	scraper *scraper.MarketNewsScraper
+}
+
+func NewAttachmentHandler(store *scraper.NewsStore, newsScraper *scraper.MarketNewsScraper) *AttachmentHandler {
+	return &AttachmentHandler{
+		store:   store,
+		scraper: newsScraper,
+	}
+}
+
+// GetNewsAttachment handles GET /api/market/news/:id/attachments/:filename
+// It serves the local copy of a PDF with Range and conditional request
+// support. A file that has not been downloaded yet is fetched from ISX
+// first.
+func (h *AttachmentHandler) GetNewsAttachment(c *gin.Context) {
+	newsID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid news id"})
+		return
+	}
+
+	att, err := h.store.FindAttachment(newsID, c.Param("filename"))
+	if err != nil {
+		if errors.Is(err, scraper.ErrAttachmentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		log.Printf("Error finding attachment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attachment"})
+		return
+	}
+
+	path := h.scraper.AttachmentPath(*att)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		log.Printf("Attachment %s not downloaded yet, fetching it from ISX", att.Filename)
+		if err := h.scraper.FetchAttachment(c.Request.Context(), newsID, att); err != nil {
+			log.Printf("Error fetching attachment %s: %v", att.Filename, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch attachment from ISX"})
+			return
+		}
+		f, err = os.Open(path)
+	}
+	if err != nil {
+		log.Printf("Error opening attachment %s: %v", path, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read attachment"})
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		log.Printf("Error reading attachment %s: %v", path, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read attachment"})
+		return
+	}
+
+	if att.SHA256 != "" {
+		c.Header("ETag", `"`+att.SHA256+`"`)
+	}
+	c.Header("Content-Type", "application/pdf")
+	c.Header("Cache-Control", attachmentMaxAge)
+	// Non-ASCII names are sent as RFC 2231 filename*=utf-8''...
+	if disposition := mime.FormatMediaType("inline", map[string]string{"filename": att.Filename}); disposition != "" {
+		c.Header("Content-Disposition", disposition)
+	}
+
+	http.ServeContent(c.Writer, c.Request, att.Filename, stat.ModTime(), f)
+}
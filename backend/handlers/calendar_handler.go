@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"isxportfolio-backend/calendar"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Range of special days returned when the request gives none
+const defaultCalendarDays = 90
+
+type CalendarHandler struct {
+	calendar *calendar.Calendar
+}
+
+func NewCalendarHandler(cal *calendar.Calendar) *CalendarHandler {
+	return &CalendarHandler{calendar: cal}
+}
+
+// GetCalendar handles GET /api/market/calendar?from=&to= (YYYY-MM-DD)
+// It returns the weekly schedule, the market status right now and the
+// holidays and shortened sessions in the range, which defaults to the next
+// 90 days. status.covered is false once the calendar has no holidays
+// listed for today.
+func (h *CalendarHandler) GetCalendar(c *gin.Context) {
+	now := time.Now().In(h.calendar.Location())
+
+	from, to := now, now.AddDate(0, 0, defaultCalendarDays)
+	if s := c.Query("from"); s != "" {
+		t, err := time.ParseInLocation(calendar.DateLayout, s, h.calendar.Location())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date in YYYY-MM-DD format"})
+			return
+		}
+		from = t
+	}
+	if s := c.Query("to"); s != "" {
+		t, err := time.ParseInLocation(calendar.DateLayout, s, h.calendar.Location())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date in YYYY-MM-DD format"})
+			return
+		}
+		to = t
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return
+	}
+
+	cfg := h.calendar.Config()
+	c.JSON(http.StatusOK, gin.H{
+		"timezone":      cfg.Timezone,
+		"weekend":       cfg.Weekend,
+		"open":          cfg.Open,
+		"close":         cfg.Close,
+		"covered_until": cfg.CoveredUntil,
+		"status": gin.H{
+			"now":                  now,
+			"covered":              h.calendar.Covers(now),
+			"is_open":              h.calendar.IsOpen(now),
+			"is_trading_day":       h.calendar.IsTradingDay(now),
+			"next_open":            h.calendar.NextOpen(now),
+			"next_close":           h.calendar.NextClose(now),
+			"previous_trading_day": h.calendar.PreviousTradingDay(now).Format(calendar.DateLayout),
+		},
+		"days": h.calendar.Days(from, to),
+	})
+}
+
+// SetCalendarDay handles PUT /api/admin/calendar/days/:date
+// The body is {"kind": "holiday"|"half_day"|"open", "close": "HH:MM", "name": "..."}
+func (h *CalendarHandler) SetCalendarDay(c *gin.Context) {
+	var day calendar.Day
+	if err := json.NewDecoder(c.Request.Body).Decode(&day); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	day.Date = c.Param("date")
+
+	day, err := h.calendar.SetDay(day, c.GetString("email"))
+	if err != nil {
+		if errors.Is(err, calendar.ErrInvalidDay) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error saving calendar day: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save calendar day"})
+		return
+	}
+
+	c.JSON(http.StatusOK, day)
+}
+
+// DeleteCalendarDay handles DELETE /api/admin/calendar/days/:date
+// It removes an admin edit, restoring the built-in entry for that date.
+func (h *CalendarHandler) DeleteCalendarDay(c *gin.Context) {
+	removed, err := h.calendar.DeleteDay(c.Param("date"))
+	if err != nil {
+		log.Printf("Error deleting calendar day: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar day"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No admin edit for that date"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
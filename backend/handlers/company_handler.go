@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"isxportfolio-backend/market"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CompanyHandler struct {
+	store *market.Store
+}
+
+// Constructor for the company handler
+func NewCompanyHandler(store *market.Store) *CompanyHandler {
+	return &CompanyHandler{store: store}
+}
+
+// ListCompanies handles GET /api/market/companies
+// Query parameters: sector and status (listed, suspended or delisted)
+func (h *CompanyHandler) ListCompanies(c *gin.Context) {
+	filter := market.CompanyFilter{
+		Sector: c.Query("sector"),
+		Status: c.Query("status"),
+	}
+	switch filter.Status {
+	case "", market.StatusListed, market.StatusSuspended, market.StatusDelisted:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be listed, suspended or delisted"})
+		return
+	}
+
+	companies, err := h.store.Companies(filter)
+	if err != nil {
+		log.Printf("Error listing companies: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch companies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"companies": companies, "total": len(companies)})
+}
+
+// GetCompany handles GET /api/market/companies/:ticker
+func (h *CompanyHandler) GetCompany(c *gin.Context) {
+	company, err := h.store.Company(c.Param("ticker"))
+	if errors.Is(err, market.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching company %s: %v", c.Param("ticker"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch company"})
+		return
+	}
+
+	c.JSON(http.StatusOK, company)
+}
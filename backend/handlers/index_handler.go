@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"isxportfolio-backend/market"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IndexHandler struct {
+	store *market.Store
+}
+
+// Constructor for the index handler
+func NewIndexHandler(store *market.Store) *IndexHandler {
+	return &IndexHandler{store: store}
+}
+
+// GetIndexHistory handles GET /api/market/indices/:code/history?from=&to=&source=
+// code is ISX60 or a sector such as BANKS. source is official or computed;
+// without it the official levels are returned when there are any.
+func (h *IndexHandler) GetIndexHistory(c *gin.Context) {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	source := c.Query("source")
+	switch source {
+	case "", market.IndexOfficial, market.IndexComputed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "source must be official or computed"})
+		return
+	}
+
+	code := strings.ToUpper(c.Param("code"))
+	sources := []string{source}
+	if source == "" {
+		sources = []string{market.IndexOfficial, market.IndexComputed}
+	}
+	var history []market.IndexPoint
+	for _, source = range sources {
+		if history, err = h.store.IndexHistory(code, source, from, to); err != nil {
+			log.Printf("Error querying history of index %s: %v", code, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch index history"})
+			return
+		}
+		if len(history) > 0 {
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": code, "source": source, "history": history})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"isxportfolio-backend/jobs"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Number of runs returned by GetJobRuns unless the request asks otherwise
+const defaultJobRuns = 20
+
+type JobHandler struct {
+	scheduler *jobs.Scheduler
+}
+
+func NewJobHandler(scheduler *jobs.Scheduler) *JobHandler {
+	return &JobHandler{scheduler: scheduler}
+}
+
+// ListJobs handles GET /api/admin/jobs
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	infos, err := h.scheduler.Jobs()
+	if err != nil {
+		log.Printf("Error listing jobs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": infos})
+}
+
+// GetJobRuns handles GET /api/admin/jobs/:name/runs?limit=
+func (h *JobHandler) GetJobRuns(c *gin.Context) {
+	limit := defaultJobRuns
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		limit = n
+	}
+
+	runs, err := h.scheduler.Runs(c.Param("name"), limit)
+	if err != nil {
+		h.jobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+// PauseJob handles POST /api/admin/jobs/:name/pause
+func (h *JobHandler) PauseJob(c *gin.Context) {
+	if err := h.scheduler.Pause(c.Param("name")); err != nil {
+		h.jobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"name": c.Param("name"), "paused": true})
+}
+
+// ResumeJob handles POST /api/admin/jobs/:name/resume
+func (h *JobHandler) ResumeJob(c *gin.Context) {
+	if err := h.scheduler.Resume(c.Param("name")); err != nil {
+		h.jobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"name": c.Param("name"), "paused": false})
+}
+
+// TriggerJob handles POST /api/admin/jobs/:name/trigger
+// The job starts in the background; its run appears in GetJobRuns.
+func (h *JobHandler) TriggerJob(c *gin.Context) {
+	if err := h.scheduler.Trigger(c.Param("name"), "manual:"+c.GetString("email")); err != nil {
+		h.jobError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"name": c.Param("name"), "started": true})
+}
+
+// jobError maps scheduler errors to responses
+func (h *JobHandler) jobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+	case errors.Is(err, jobs.ErrJobRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Job scheduler error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Job scheduler error"})
+	}
+}
@@ -1,51 +1,365 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"isxportfolio-backend/classifier"
 	"isxportfolio-backend/scraper"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Interval of the comments sent to keep idle news streams open through proxies
+const newsStreamKeepAlive = 15 * time.Second
+
+// Number of recent runs included in the scraper health report
+const scraperHealthRuns = 20
+
 type MarketNewsHandler struct {
-	scraper *scraper.MarketNewsScraper
+	store       *scraper.NewsStore
+	coordinator *scraper.RunCoordinator
+	classifier  *classifier.Classifier
+	broker      *scraper.NewsBroker
 }
 
-func NewMarketNewsHandler() *MarketNewsHandler {
+func NewMarketNewsHandler(store *scraper.NewsStore, coordinator *scraper.RunCoordinator, classifier *classifier.Classifier, broker *scraper.NewsBroker) *MarketNewsHandler {
 	return &MarketNewsHandler{
-		scraper: scraper.NewMarketNewsScraper(
-			"/app/data/market_news.csv", // Updated path for Docker
-			"/app/data/pdfs",            // Updated path for Docker
-		),
+		store:       store,
+		coordinator: coordinator,
+		classifier:  classifier,
+		broker:      broker,
 	}
 }
 
 // GetMarketNews handles GET /api/market/news
+// Query parameters: ticker, category, from, to (YYYY-MM-DD), q, lang,
+// has_attachments, page, limit and cursor. Without lang the language is
+// taken from the Accept-Language header.
 func (h *MarketNewsHandler) GetMarketNews(c *gin.Context) {
-	// Run the scraper to get latest news
-	err := h.scraper.Run()
+	filter, err := parseNewsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.Category != "" && !h.classifier.IsCategory(filter.Category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown category: " + filter.Category})
+		return
+	}
+
+	page, err := h.store.Query(filter)
 	if err != nil {
+		if errors.Is(err, scraper.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error querying market news: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch market news",
 		})
 		return
 	}
 
-	// Return all items from the scraper
-	c.JSON(http.StatusOK, h.scraper.AllItems)
+	c.JSON(http.StatusOK, page)
 }
 
-// RefreshMarketNews handles POST /api/market/news/refresh
-func (h *MarketNewsHandler) RefreshMarketNews(c *gin.Context) {
-	err := h.scraper.Run()
+// GetNewsCategories handles GET /api/market/news/categories
+func (h *MarketNewsHandler) GetNewsCategories(c *gin.Context) {
+	categories := []gin.H{}
+	for _, cat := range h.classifier.Categories() {
+		categories = append(categories, gin.H{
+			"id":      cat.ID,
+			"name_ar": cat.NameAr,
+			"name_en": cat.NameEn,
+		})
+	}
+	categories = append(categories, gin.H{
+		"id":      classifier.Other,
+		"name_ar": "أخرى",
+		"name_en": "Other",
+	})
+
+	c.JSON(http.StatusOK, gin.H{"categories": categories})
+}
+
+// SearchMarketNews handles GET /api/market/news/search?q=
+// It matches news titles and the text of their PDF attachments.
+func (h *MarketNewsHandler) SearchMarketNews(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	results, err := h.store.Search(query, limit)
 	if err != nil {
+		if errors.Is(err, scraper.ErrSearchDisabled) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error searching market news: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to refresh market news",
+			"error": "Failed to search market news",
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Market news refreshed successfully",
+		"query":   query,
+		"results": results,
+	})
+}
+
+// StreamMarketNews handles GET /api/market/news/stream
+// It pushes newly discovered news as Server-Sent Events. Clients can filter
+// by ticker and lang and resume with the Last-Event-ID header or last_event_id
+// query parameter.
+func (h *MarketNewsHandler) StreamMarketNews(c *gin.Context) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var lastID int64
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || id < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Last-Event-ID must be a news item id"})
+			return
+		}
+		lastID = id
+	}
+
+	lang, err := requestLanguage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, backlog, err := h.broker.Subscribe(c.Query("ticker"), lang, lastID)
+	if err != nil {
+		log.Printf("Error subscribing to news stream: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open news stream"})
+		return
+	}
+	defer h.broker.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// Ask browsers to reconnect quickly if the connection drops
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	for _, item := range backlog {
+		if err := writeNewsEvent(c.Writer, item); err != nil {
+			return
+		}
+		lastID = item.ID
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(newsStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case item, ok := <-sub.Items:
+			if !ok {
+				// Dropped for falling behind, the client will resume
+				return
+			}
+			// Items published while subscribing may already be in the backlog
+			if item.ID <= lastID {
+				continue
+			}
+			if err := writeNewsEvent(c.Writer, item); err != nil {
+				return
+			}
+			lastID = item.ID
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeNewsEvent writes one news item as an SSE "news" event
+func writeNewsEvent(w io.Writer, item scraper.NewsItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: news\ndata: %s\n\n", item.ID, data)
+	return err
+}
+
+// RefreshMarketNews handles POST /api/market/news/refresh
+// It starts a scraper run, or joins the one in progress, and returns
+// without waiting for it to finish.
+func (h *MarketNewsHandler) RefreshMarketNews(c *gin.Context) {
+	run, started := h.coordinator.Trigger("api")
+	info := run.Info()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"run_id":  info.ID,
+		"status":  info.Status,
+		"started": started,
 	})
 }
+
+// BackfillMarketNews handles POST /api/admin/news/backfill?until=YYYY-MM-DD
+// It walks the ISX story lists back to the given date in the background.
+// The backfill can be stopped with POST /api/admin/news/runs/:id/cancel.
+func (h *MarketNewsHandler) BackfillMarketNews(c *gin.Context) {
+	until, err := time.Parse("2006-01-02", c.Query("until"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "until must be a date in YYYY-MM-DD format"})
+		return
+	}
+
+	run, started := h.coordinator.TriggerBackfill("api", until)
+	info := run.Info()
+	if !started && info.Kind != scraper.RunKindBackfill {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "Another scraper run is in progress",
+			"run_id": info.ID,
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"run_id":  info.ID,
+		"status":  info.Status,
+		"started": started,
+	})
+}
+
+// GetScraperRun handles GET /api/market/news/runs/:id
+func (h *MarketNewsHandler) GetScraperRun(c *gin.Context) {
+	run, ok := h.coordinator.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Run not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, run.Info())
+}
+
+// CancelScraperRun handles POST /api/admin/news/runs/:id/cancel
+// It asks a run in progress to stop and returns without waiting for it.
+func (h *MarketNewsHandler) CancelScraperRun(c *gin.Context) {
+	run, ok := h.coordinator.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Run not found"})
+		return
+	}
+	if !run.Cancel() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Run already finished"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, run.Info())
+}
+
+// GetScraperHealth handles GET /api/admin/scraper/health
+// It reports whether recent scraper runs extracted what they usually do.
+func (h *MarketNewsHandler) GetScraperHealth(c *gin.Context) {
+	report, err := h.store.ScraperHealth(scraperHealthRuns)
+	if err != nil {
+		log.Printf("Error reading scraper health: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read scraper health"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseNewsFilter reads the news list query parameters
+func parseNewsFilter(c *gin.Context) (scraper.NewsFilter, error) {
+	filter := scraper.NewsFilter{
+		Ticker:   c.Query("ticker"),
+		Category: c.Query("category"),
+		Query:    c.Query("q"),
+		Cursor:   c.Query("cursor"),
+	}
+
+	lang, err := requestLanguage(c)
+	if err != nil {
+		return filter, err
+	}
+	filter.Lang = lang
+
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return filter, errors.New("from must be a date in YYYY-MM-DD format")
+		}
+		filter.From = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return filter, errors.New("to must be a date in YYYY-MM-DD format")
+		}
+		// Include the whole "to" day
+		filter.To = t.AddDate(0, 0, 1)
+	}
+	if hasAttachments := c.Query("has_attachments"); hasAttachments != "" {
+		b, err := strconv.ParseBool(hasAttachments)
+		if err != nil {
+			return filter, errors.New("has_attachments must be true or false")
+		}
+		filter.HasAttachments = &b
+	}
+	if page := c.Query("page"); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			return filter, errors.New("page must be a positive integer")
+		}
+		filter.Page = n
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return filter, errors.New("limit must be a positive integer")
+		}
+		filter.Limit = n
+	}
+
+	return filter, nil
+}
+
+// requestLanguage returns the news language asked for by the lang query
+// parameter or, failing that, the Accept-Language header. Arabic is the
+// default.
+func requestLanguage(c *gin.Context) (string, error) {
+	if lang := strings.ToLower(c.Query("lang")); lang != "" {
+		if lang != scraper.LangArabic && lang != scraper.LangEnglish {
+			return "", errors.New("lang must be ar or en")
+		}
+		return lang, nil
+	}
+
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		tag := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		switch {
+		case strings.HasPrefix(tag, scraper.LangArabic):
+			return scraper.LangArabic, nil
+		case strings.HasPrefix(tag, scraper.LangEnglish):
+			return scraper.LangEnglish, nil
+		}
+	}
+	return scraper.LangArabic, nil
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"isxportfolio-backend/indicators"
+	"isxportfolio-backend/market"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PriceHandler struct {
+	store   *market.Store
+	scraper *market.PriceScraper
+	// adjuster serves adjusted=true requests; they are refused while it is nil
+	adjuster market.PriceAdjuster
+}
+
+// Constructor for the price handler. Adjusted prices use the store's
+// corporate action factors.
+func NewPriceHandler(store *market.Store, scraper *market.PriceScraper) *PriceHandler {
+	return &PriceHandler{store: store, scraper: scraper, adjuster: store}
+}
+
+// GetPrices handles GET /api/market/tickers/:ticker/prices?from=&to= (YYYY-MM-DD)
+// It returns the ticker's daily trading results, oldest first, adjusted for
+// bonus shares and rights issues with adjusted=true
+func (h *PriceHandler) GetPrices(c *gin.Context) {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adjuster, ok := h.requestAdjuster(c)
+	if !ok {
+		return
+	}
+
+	ticker := strings.ToUpper(c.Param("ticker"))
+	prices, err := h.store.Prices(ticker, from, to)
+	if err == nil && adjuster != nil {
+		prices, err = adjuster.Adjust(ticker, prices)
+	}
+	if err != nil {
+		log.Printf("Error querying prices of %s: %v", ticker, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ticker": ticker, "adjusted": adjuster != nil, "prices": prices})
+}
+
+// GetCandles handles GET /api/market/tickers/:ticker/candles?interval=1d|1w|1M&from=&to=
+// Optional parameters: adjusted=true for prices adjusted for corporate
+// actions and format=columnar for {"t": [...], "o": [...], ...} instead of a
+// list of bars.
+func (h *PriceHandler) GetCandles(c *gin.Context) {
+	interval := c.DefaultQuery("interval", market.IntervalDay)
+	if !market.ValidInterval(interval) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": market.ErrInvalidInterval.Error()})
+		return
+	}
+	format := c.DefaultQuery("format", "rows")
+	if format != "rows" && format != "columnar" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be rows or columnar"})
+		return
+	}
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adjuster, ok := h.requestAdjuster(c)
+	if !ok {
+		return
+	}
+
+	ticker := strings.ToUpper(c.Param("ticker"))
+	candles, err := h.store.Candles(ticker, interval, from, to, adjuster)
+	if err != nil {
+		log.Printf("Error building candles of %s: %v", ticker, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch candles"})
+		return
+	}
+
+	resp := gin.H{"ticker": ticker, "interval": interval, "adjusted": adjuster != nil}
+	if format == "columnar" {
+		resp["candles"] = market.Columns(candles)
+	} else {
+		resp["candles"] = candles
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Most indicators that can be computed in one request
+const maxIndicatorSet = 10
+
+// GetIndicators handles GET /api/market/tickers/:ticker/indicators?set=rsi14,sma50&from=&to=
+// Each indicator is computed over the ticker's whole history up to to, so
+// values at from are already warmed up, and returned for the sessions from
+// from on. Values are null while an indicator is still warming up.
+// Cumulative indicators (obv, vwap) are anchored to the first session
+// returned instead, so they do not depend on how much history is stored.
+// adjusted=true computes them over adjusted prices.
+func (h *PriceHandler) GetIndicators(c *gin.Context) {
+	var specs []string
+	for _, spec := range strings.Split(c.Query("set"), ",") {
+		if spec = strings.ToLower(strings.TrimSpace(spec)); spec != "" {
+			specs = append(specs, spec)
+		}
+	}
+	if len(specs) == 0 || len(specs) > maxIndicatorSet {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "set must list between 1 and 10 indicators"})
+		return
+	}
+	streams := make([]indicators.Stream, len(specs))
+	for i, spec := range specs {
+		stream, err := indicators.Parse(spec)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		streams[i] = stream
+	}
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adjuster, ok := h.requestAdjuster(c)
+	if !ok {
+		return
+	}
+
+	ticker := strings.ToUpper(c.Param("ticker"))
+	prices, err := h.store.Prices(ticker, time.Time{}, to)
+	if err == nil && adjuster != nil {
+		prices, err = adjuster.Adjust(ticker, prices)
+	}
+	if err != nil {
+		log.Printf("Error querying prices of %s: %v", ticker, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prices"})
+		return
+	}
+
+	first := ""
+	if !from.IsZero() {
+		first = from.Format(market.DateLayout)
+	}
+	dates := []string{}
+	series := make([]map[string][]*float64, len(streams))
+	for i, stream := range streams {
+		series[i] = make(map[string][]*float64)
+		for _, name := range stream.Outputs() {
+			series[i][name] = []*float64{}
+		}
+	}
+	for _, p := range prices {
+		bar := indicators.Bar{High: p.High, Low: p.Low, Close: p.Close, Volume: float64(p.Volume)}
+		keep := p.Date >= first
+		if keep && len(dates) == 0 {
+			for _, stream := range streams {
+				if cumulative, ok := stream.(indicators.Cumulative); ok {
+					cumulative.Reset()
+				}
+			}
+		}
+		if keep {
+			dates = append(dates, p.Date)
+		}
+		for i, stream := range streams {
+			values := stream.Next(bar)
+			if !keep {
+				continue
+			}
+			for j, name := range stream.Outputs() {
+				var v *float64
+				if values != nil {
+					v = &values[j]
+				}
+				series[i][name] = append(series[i][name], v)
+			}
+		}
+	}
+
+	result := make(gin.H, len(specs))
+	for i, spec := range specs {
+		result[spec] = series[i]
+	}
+	c.JSON(http.StatusOK, gin.H{"ticker": ticker, "adjusted": adjuster != nil, "dates": dates, "indicators": result})
+}
+
+// requestAdjuster returns the adjuster to use when the request asks for
+// adjusted=true, or nil for raw prices. It writes an error response and
+// returns false if the request cannot be served.
+func (h *PriceHandler) requestAdjuster(c *gin.Context) (market.PriceAdjuster, bool) {
+	switch c.DefaultQuery("adjusted", "false") {
+	case "false":
+		return nil, true
+	case "true":
+		if h.adjuster == nil {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": "Adjusted prices are not available"})
+			return nil, false
+		}
+		return h.adjuster, true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "adjusted must be true or false"})
+		return nil, false
+	}
+}
+
+// BackfillPrices handles POST /api/admin/prices/backfill
+// The body is {"from": "YYYY-MM-DD", "to": "YYYY-MM-DD", "tickers": [...]};
+// to defaults to today and tickers to every listed company.
+func (h *PriceHandler) BackfillPrices(c *gin.Context) {
+	var req struct {
+		From    string   `json:"from"`
+		To      string   `json:"to"`
+		Tickers []string `json:"tickers"`
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	from, err := time.Parse(market.DateLayout, req.From)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date in YYYY-MM-DD format"})
+		return
+	}
+	to := time.Now()
+	if req.To != "" {
+		if to, err = time.Parse(market.DateLayout, req.To); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date in YYYY-MM-DD format"})
+			return
+		}
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return
+	}
+	for i, ticker := range req.Tickers {
+		req.Tickers[i] = strings.ToUpper(strings.TrimSpace(ticker))
+	}
+
+	if err := h.scraper.StartBackfill(req.Tickers, from, to); err != nil {
+		if errors.Is(err, market.ErrBackfillRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start price backfill"})
+		return
+	}
+
+	log.Printf("Price backfill from %s to %s started by %s", req.From, to.Format(market.DateLayout), c.GetString("email"))
+	c.JSON(http.StatusAccepted, gin.H{"message": "Price backfill started"})
+}
+
+// parseDateRange reads the optional from and to query parameters
+func parseDateRange(c *gin.Context) (from, to time.Time, err error) {
+	if s := c.Query("from"); s != "" {
+		if from, err = time.Parse(market.DateLayout, s); err != nil {
+			return from, to, errors.New("from must be a date in YYYY-MM-DD format")
+		}
+	}
+	if s := c.Query("to"); s != "" {
+		if to, err = time.Parse(market.DateLayout, s); err != nil {
+			return from, to, errors.New("to must be a date in YYYY-MM-DD format")
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return from, to, errors.New("to must not be before from")
+	}
+	return from, to, nil
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"isxportfolio-backend/market"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type QuoteHandler struct {
+	cache *market.QuoteCache
+}
+
+// Constructor for the quote handler
+func NewQuoteHandler(cache *market.QuoteCache) *QuoteHandler {
+	return &QuoteHandler{cache: cache}
+}
+
+// GetQuotes handles GET /api/market/quotes?tickers=BBOB,TASC
+// It returns the latest live quotes from memory, for every ticker when none
+// are given. as_of is the time of the newest quote, null before the first poll.
+func (h *QuoteHandler) GetQuotes(c *gin.Context) {
+	var tickers []string
+	for _, t := range strings.Split(c.Query("tickers"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tickers = append(tickers, t)
+		}
+	}
+
+	quotes, asOf := h.cache.Get(tickers)
+	var asOfValue interface{}
+	if !asOf.IsZero() {
+		asOfValue = asOf
+	}
+	c.JSON(http.StatusOK, gin.H{"as_of": asOfValue, "quotes": quotes})
+}
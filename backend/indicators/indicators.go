@@ -0,0 +1,166 @@
+// Package indicators computes technical analysis indicators over daily
+// price bars. Every indicator is a stream: bars are fed one at a time,
+// oldest first, and each call returns the indicator's value for that bar
+// once enough bars have been seen, so a series can be extended with new
+// sessions without recomputing its history.
+package indicators
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Bar is one session's prices and traded shares
+type Bar struct {
+	High   float64
+	Low    float64
+	Close  float64
+	Volume float64
+}
+
+// Stream is the common form of every indicator, used to compute a set of
+// them named by their specs
+type Stream interface {
+	// Next consumes the next bar and returns one value per output, or nil
+	// while the indicator is warming up
+	Next(b Bar) []float64
+	// Outputs names the values returned by Next
+	Outputs() []string
+}
+
+// Cumulative is implemented by indicators that accumulate over every bar
+// they have seen rather than over a period, obv and vwap. Their values
+// depend on where they start, so Reset lets callers anchor them to a date.
+type Cumulative interface {
+	Reset()
+}
+
+// Largest period accepted in a spec
+const maxPeriod = 1000
+
+var specPattern = regexp.MustCompile(`^([a-z]+)(\d+(?:_\d+(?:\.\d+)?)*)?$`)
+
+// Parse builds the indicator named by a spec such as sma50, ema20, rsi14,
+// macd12_26_9, bb20_2, atr14, obv or vwap. Parameters after the first are
+// separated by underscores; rsi, atr, macd and bb have the usual defaults.
+func Parse(spec string) (Stream, error) {
+	m := specPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(spec)))
+	if m == nil {
+		return nil, fmt.Errorf("invalid indicator %q", spec)
+	}
+	name := m[1]
+	var params []float64
+	if m[2] != "" {
+		for _, p := range strings.Split(m[2], "_") {
+			f, err := strconv.ParseFloat(p, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid indicator %q", spec)
+			}
+			params = append(params, f)
+		}
+	}
+
+	// period reads parameter i as a period, or def when it is missing
+	period := func(i, def int) (int, error) {
+		if i >= len(params) {
+			if def == 0 {
+				return 0, fmt.Errorf("indicator %q needs a period", spec)
+			}
+			return def, nil
+		}
+		n := params[i]
+		if n < 1 || n > maxPeriod || n != float64(int(n)) {
+			return 0, fmt.Errorf("indicator %q has an invalid period", spec)
+		}
+		return int(n), nil
+	}
+	maxParams := map[string]int{"sma": 1, "ema": 1, "rsi": 1, "atr": 1, "bb": 2, "macd": 3, "obv": 0, "vwap": 0}
+	if limit, ok := maxParams[name]; !ok {
+		return nil, fmt.Errorf("unknown indicator %q", spec)
+	} else if len(params) > limit {
+		return nil, fmt.Errorf("indicator %q has too many parameters", spec)
+	}
+
+	switch name {
+	case "sma":
+		n, err := period(0, 0)
+		if err != nil {
+			return nil, err
+		}
+		return closeStream{NewSMA(n)}, nil
+	case "ema":
+		n, err := period(0, 0)
+		if err != nil {
+			return nil, err
+		}
+		return closeStream{NewEMA(n)}, nil
+	case "rsi":
+		n, err := period(0, 14)
+		if err != nil {
+			return nil, err
+		}
+		return closeStream{NewRSI(n)}, nil
+	case "atr":
+		n, err := period(0, 14)
+		if err != nil {
+			return nil, err
+		}
+		return NewATR(n), nil
+	case "bb":
+		n, err := period(0, 20)
+		if err != nil {
+			return nil, err
+		}
+		k := 2.0
+		if len(params) > 1 {
+			if k = params[1]; k <= 0 {
+				return nil, fmt.Errorf("indicator %q has an invalid width", spec)
+			}
+		}
+		return NewBollinger(n, k), nil
+	case "macd":
+		fast, err := period(0, 12)
+		if err != nil {
+			return nil, err
+		}
+		slow, err := period(1, 26)
+		if err != nil {
+			return nil, err
+		}
+		signal, err := period(2, 9)
+		if err != nil {
+			return nil, err
+		}
+		if fast >= slow {
+			return nil, fmt.Errorf("indicator %q needs a fast period below the slow one", spec)
+		}
+		return NewMACD(fast, slow, signal), nil
+	case "obv":
+		return NewOBV(), nil
+	default:
+		return NewVWAP(), nil
+	}
+}
+
+// closeAdder is an indicator computed from closing prices alone
+type closeAdder interface {
+	Add(close float64) (float64, bool)
+}
+
+// closeStream adapts a single-value indicator over closes to Stream
+type closeStream struct {
+	ind closeAdder
+}
+
+func (s closeStream) Next(b Bar) []float64 {
+	if v, ok := s.ind.Add(b.Close); ok {
+		return []float64{v}
+	}
+	return nil
+}
+
+func (s closeStream) Outputs() []string {
+	return []string{"value"}
+}
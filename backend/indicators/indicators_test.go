@@ -0,0 +1,298 @@
+package indicators
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// referenceCloses is the closing price series of Wilder's RSI worked
+// example, extended to 40 sessions so MACD 12/26/9 has values. The expected
+// series below were computed independently from the textbook definitions.
+var referenceCloses = []float64{
+	44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08,
+	45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64,
+	46.21, 46.25, 45.71, 46.45, 45.78, 45.35, 44.03, 44.18, 44.22, 44.57,
+	43.42, 42.66, 43.13, 43.90, 44.35, 44.65, 45.12, 44.80, 45.40, 45.95,
+}
+
+func closeBars(closes []float64) []Bar {
+	bars := make([]Bar, len(closes))
+	for i, c := range closes {
+		bars[i] = Bar{High: c + 1, Low: c - 1, Close: c, Volume: 1}
+	}
+	return bars
+}
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+func TestRSIWilderExample(t *testing.T) {
+	// Published spreadsheets round the averages at each step and show
+	// 70.53, 66.32, ...; these are the unrounded values
+	want := []float64{
+		70.4641, 66.2496, 66.4809, 69.3469, 66.2947, 57.9150, 62.8807, 63.2088, 56.0116,
+		62.3399, 54.6710, 50.3868, 40.0194, 41.4926, 41.9024, 45.4995, 37.3228, 33.0905,
+		37.7888, 44.6464, 48.2375, 50.5412, 53.9955, 51.3651, 55.7208, 59.3175,
+	}
+
+	rsi := NewRSI(14)
+	var got []float64
+	for i, c := range referenceCloses {
+		v, ok := rsi.Add(c)
+		if ok != (i >= 14) {
+			t.Fatalf("bar %d: ready = %v", i, ok)
+		}
+		if ok {
+			got = append(got, v)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d values, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !near(got[i], want[i], 1e-3) {
+			t.Errorf("RSI at bar %d = %.4f, want %.4f", i+14, got[i], want[i])
+		}
+	}
+}
+
+func TestRSIFlatAndRising(t *testing.T) {
+	flat, rising := NewRSI(3), NewRSI(3)
+	var f, r float64
+	for i := 0; i < 5; i++ {
+		f, _ = flat.Add(10)
+		r, _ = rising.Add(float64(10 + i))
+	}
+	if f != 50 || r != 100 {
+		t.Errorf("flat RSI = %v, rising RSI = %v, want 50 and 100", f, r)
+	}
+}
+
+func TestMACDReference(t *testing.T) {
+	want := map[int]MACDValue{
+		33: {MACD: -0.470174, Signal: -0.144895, Histogram: -0.325279},
+		34: {MACD: -0.425383, Signal: -0.200993, Histogram: -0.224390},
+		35: {MACD: -0.361510, Signal: -0.233096, Histogram: -0.128414},
+		36: {MACD: -0.269855, Signal: -0.240448, Histogram: -0.029407},
+		37: {MACD: -0.220497, Signal: -0.236458, Histogram: 0.015960},
+		38: {MACD: -0.131451, Signal: -0.215456, Histogram: 0.084006},
+		39: {MACD: -0.016312, Signal: -0.175628, Histogram: 0.159315},
+	}
+
+	macd := NewMACD(12, 26, 9)
+	for i, c := range referenceCloses {
+		v, ok := macd.Add(c)
+		w, ready := want[i]
+		if ok != ready {
+			t.Fatalf("bar %d: ready = %v, want %v", i, ok, ready)
+		}
+		if !ok {
+			continue
+		}
+		if !near(v.MACD, w.MACD, 1e-5) || !near(v.Signal, w.Signal, 1e-5) || !near(v.Histogram, w.Histogram, 1e-5) {
+			t.Errorf("MACD at bar %d = %+v, want %+v", i, v, w)
+		}
+	}
+}
+
+func TestBollingerReference(t *testing.T) {
+	want := map[int]Band{
+		19: {Upper: 47.115328, Middle: 45.409, Lower: 43.702672},
+		20: {Upper: 47.168740, Middle: 45.5025, Lower: 43.836260},
+		30: {Upper: 47.335247, Middle: 45.5335, Lower: 43.731753},
+		39: {Upper: 46.921336, Middle: 44.8065, Lower: 42.691664},
+	}
+
+	bb := NewBollinger(20, 2)
+	for i, c := range referenceCloses {
+		v, ok := bb.Add(c)
+		if ok != (i >= 19) {
+			t.Fatalf("bar %d: ready = %v", i, ok)
+		}
+		w, check := want[i]
+		if !check {
+			continue
+		}
+		if !near(v.Upper, w.Upper, 1e-5) || !near(v.Middle, w.Middle, 1e-5) || !near(v.Lower, w.Lower, 1e-5) {
+			t.Errorf("Bollinger at bar %d = %+v, want %+v", i, v, w)
+		}
+	}
+}
+
+func TestSMAAndEMA(t *testing.T) {
+	sma, ema := NewSMA(3), NewEMA(3)
+	closes := []float64{2, 4, 6, 8, 12}
+	wantSMA := []float64{0, 0, 4, 6, 26.0 / 3}
+	// Seeded with the SMA, then smoothed by 2 / (3 + 1)
+	wantEMA := []float64{0, 0, 4, 6, 9}
+	for i, c := range closes {
+		s, sOK := sma.Add(c)
+		e, eOK := ema.Add(c)
+		if sOK != (i >= 2) || eOK != (i >= 2) {
+			t.Fatalf("bar %d: ready = %v, %v", i, sOK, eOK)
+		}
+		if sOK && (!near(s, wantSMA[i], 1e-9) || !near(e, wantEMA[i], 1e-9)) {
+			t.Errorf("bar %d: SMA = %v, EMA = %v, want %v and %v", i, s, e, wantSMA[i], wantEMA[i])
+		}
+	}
+}
+
+func TestATR(t *testing.T) {
+	bars := []Bar{
+		{High: 10, Low: 8, Close: 9},     // TR 2, the first bar's range
+		{High: 11, Low: 9, Close: 10},    // TR 2
+		{High: 12, Low: 9, Close: 11},    // TR 3
+		{High: 11, Low: 10, Close: 10.5}, // TR 1, high to previous close is 0
+		{High: 14, Low: 11, Close: 13},   // TR 3.5, from the previous close
+	}
+	want := []float64{0, 0, 7.0 / 3, 17.0 / 9, 65.5 / 27}
+
+	atr := NewATR(3)
+	for i, b := range bars {
+		v, ok := atr.Add(b)
+		if ok != (i >= 2) {
+			t.Fatalf("bar %d: ready = %v", i, ok)
+		}
+		if ok && !near(v, want[i], 1e-9) {
+			t.Errorf("ATR at bar %d = %v, want %v", i, v, want[i])
+		}
+	}
+}
+
+func TestOBV(t *testing.T) {
+	bars := []Bar{
+		{Close: 10, Volume: 20},
+		{Close: 11, Volume: 30},
+		{Close: 10.5, Volume: 5},
+		{Close: 10.5, Volume: 7},
+		{Close: 12, Volume: 10},
+	}
+	want := []float64{0, 30, 25, 25, 35}
+
+	obv := NewOBV()
+	for i, b := range bars {
+		if got := obv.Add(b); got != want[i] {
+			t.Errorf("OBV at bar %d = %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+func TestVWAP(t *testing.T) {
+	vwap := NewVWAP()
+	if _, ok := vwap.Add(Bar{High: 5, Low: 5, Close: 5, Volume: 0}); ok {
+		t.Error("VWAP has a value before any volume traded")
+	}
+
+	bars := []Bar{
+		{High: 3, Low: 1, Close: 2, Volume: 10}, // typical price 2
+		{High: 4, Low: 2, Close: 3, Volume: 30}, // typical price 3
+		{High: 2, Low: 2, Close: 2, Volume: 0},
+	}
+	want := []float64{2, 2.75, 2.75}
+	for i, b := range bars {
+		v, ok := vwap.Add(b)
+		if !ok || !near(v, want[i], 1e-9) {
+			t.Errorf("VWAP at bar %d = %v, %v, want %v", i, v, ok, want[i])
+		}
+	}
+}
+
+func TestCumulativeReset(t *testing.T) {
+	obv, vwap := NewOBV(), NewVWAP()
+	for _, b := range []Bar{
+		{High: 10, Low: 10, Close: 10, Volume: 100},
+		{High: 12, Low: 12, Close: 12, Volume: 50},
+	} {
+		obv.Add(b)
+		vwap.Add(b)
+	}
+
+	for _, s := range []Stream{obv, vwap} {
+		s.(Cumulative).Reset()
+	}
+	bars := []Bar{
+		{High: 3, Low: 1, Close: 2, Volume: 10},
+		{High: 4, Low: 2, Close: 3, Volume: 30},
+	}
+	wantOBV := []float64{0, 30}
+	wantVWAP := []float64{2, 2.75}
+	for i, b := range bars {
+		o := obv.Add(b)
+		v, _ := vwap.Add(b)
+		if o != wantOBV[i] || !near(v, wantVWAP[i], 1e-9) {
+			t.Errorf("bar %d after reset: OBV = %v, VWAP = %v, want %v and %v", i, o, v, wantOBV[i], wantVWAP[i])
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		outputs []string
+		warmup  int // bars without a value
+	}{
+		{"sma5", []string{"value"}, 4},
+		{" SMA5 ", []string{"value"}, 4},
+		{"ema20", []string{"value"}, 19},
+		{"rsi", []string{"value"}, 14},
+		{"rsi7", []string{"value"}, 7},
+		{"atr", []string{"value"}, 13},
+		{"atr5", []string{"value"}, 4},
+		{"bb", []string{"upper", "middle", "lower"}, 19},
+		{"bb10_1.5", []string{"upper", "middle", "lower"}, 9},
+		{"macd", []string{"macd", "signal", "histogram"}, 33},
+		{"macd5_10_3", []string{"macd", "signal", "histogram"}, 11},
+		{"obv", []string{"value"}, 0},
+		{"vwap", []string{"value"}, 0},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(s.Outputs(), tt.outputs) {
+			t.Errorf("Parse(%q) outputs = %v, want %v", tt.spec, s.Outputs(), tt.outputs)
+		}
+		warmup := -1
+		for i, b := range closeBars(referenceCloses) {
+			v := s.Next(b)
+			if v != nil && len(v) != len(tt.outputs) {
+				t.Errorf("Parse(%q) returned %d values, want %d", tt.spec, len(v), len(tt.outputs))
+			}
+			if v != nil {
+				warmup = i
+				break
+			}
+		}
+		if warmup != tt.warmup {
+			t.Errorf("Parse(%q) first value at bar %d, want %d", tt.spec, warmup, tt.warmup)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"foo10",
+		"1sma",
+		"sma",       // needs a period
+		"ema",       // needs a period
+		"sma0",      // period below 1
+		"sma1001",   // period above maxPeriod
+		"sma2.5",    // fractional period
+		"sma5_",     // empty parameter
+		"ema-5",     // negative period
+		"rsi14_2",   // too many parameters
+		"obv5",      // no parameters
+		"macd26_12", // fast above slow
+		"macd12_12",
+		"bb20_0", // zero width
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
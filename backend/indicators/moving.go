@@ -0,0 +1,54 @@
+package indicators
+
+// SMA is the simple moving average of the last n values
+type SMA struct {
+	n      int
+	window []float64
+	next   int
+	count  int
+	sum    float64
+}
+
+// Constructor for a simple moving average over n values
+func NewSMA(n int) *SMA {
+	return &SMA{n: n, window: make([]float64, n)}
+}
+
+// Add consumes a value and returns the average once n values have been seen
+func (s *SMA) Add(x float64) (float64, bool) {
+	s.sum += x - s.window[s.next]
+	s.window[s.next] = x
+	s.next = (s.next + 1) % s.n
+	if s.count < s.n {
+		s.count++
+	}
+	if s.count < s.n {
+		return 0, false
+	}
+	return s.sum / float64(s.n), true
+}
+
+// EMA is the exponential moving average with smoothing 2 / (n + 1),
+// seeded with the simple average of the first n values
+type EMA struct {
+	n     int
+	alpha float64
+	count int
+	value float64
+}
+
+// Constructor for an exponential moving average over n values
+func NewEMA(n int) *EMA {
+	return &EMA{n: n, alpha: 2 / float64(n+1)}
+}
+
+// Add consumes a value and returns the average once n values have been seen
+func (e *EMA) Add(x float64) (float64, bool) {
+	if e.count < e.n {
+		e.count++
+		e.value += (x - e.value) / float64(e.count)
+		return e.value, e.count == e.n
+	}
+	e.value += e.alpha * (x - e.value)
+	return e.value, true
+}
@@ -0,0 +1,95 @@
+package indicators
+
+// RSI is Wilder's relative strength index over n changes
+type RSI struct {
+	n       int
+	count   int
+	prev    float64
+	avgGain float64
+	avgLoss float64
+}
+
+// Constructor for a relative strength index over n changes
+func NewRSI(n int) *RSI {
+	return &RSI{n: n}
+}
+
+// Add consumes a close and returns the index, from 0 to 100, once n
+// changes have been seen
+func (r *RSI) Add(close float64) (float64, bool) {
+	r.count++
+	if r.count == 1 {
+		r.prev = close
+		return 0, false
+	}
+	change := close - r.prev
+	r.prev = close
+	gain, loss := max(change, 0), max(-change, 0)
+
+	changes := r.count - 1
+	if changes <= r.n {
+		// The first averages are plain means of the first n changes
+		r.avgGain += gain / float64(r.n)
+		r.avgLoss += loss / float64(r.n)
+		if changes < r.n {
+			return 0, false
+		}
+	} else {
+		r.avgGain = (r.avgGain*float64(r.n-1) + gain) / float64(r.n)
+		r.avgLoss = (r.avgLoss*float64(r.n-1) + loss) / float64(r.n)
+	}
+
+	if r.avgLoss == 0 {
+		if r.avgGain == 0 {
+			return 50, true
+		}
+		return 100, true
+	}
+	return 100 - 100/(1+r.avgGain/r.avgLoss), true
+}
+
+// MACDValue is one value of a MACD
+type MACDValue struct {
+	MACD      float64
+	Signal    float64
+	Histogram float64
+}
+
+// MACD is the difference between a fast and a slow EMA of closes, with an
+// EMA of that difference as its signal line
+type MACD struct {
+	fast   *EMA
+	slow   *EMA
+	signal *EMA
+}
+
+// Constructor for a MACD with the given EMA periods, usually 12, 26 and 9
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{fast: NewEMA(fast), slow: NewEMA(slow), signal: NewEMA(signal)}
+}
+
+// Add consumes a close and returns the MACD once the signal line is ready
+func (m *MACD) Add(close float64) (MACDValue, bool) {
+	fast, _ := m.fast.Add(close)
+	slow, ok := m.slow.Add(close)
+	if !ok {
+		return MACDValue{}, false
+	}
+	line := fast - slow
+	signal, ok := m.signal.Add(line)
+	if !ok {
+		return MACDValue{}, false
+	}
+	return MACDValue{MACD: line, Signal: signal, Histogram: line - signal}, true
+}
+
+func (m *MACD) Next(b Bar) []float64 {
+	if v, ok := m.Add(b.Close); ok {
+		return []float64{v.MACD, v.Signal, v.Histogram}
+	}
+	return nil
+}
+
+func (m *MACD) Outputs() []string {
+	return []string{"macd", "signal", "histogram"}
+}
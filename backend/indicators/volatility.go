@@ -0,0 +1,87 @@
+package indicators
+
+import "math"
+
+// Band is one value of Bollinger Bands
+type Band struct {
+	Upper  float64
+	Middle float64
+	Lower  float64
+}
+
+// Bollinger is a simple moving average of closes with bands k population
+// standard deviations above and below it
+type Bollinger struct {
+	k     float64
+	sma   *SMA
+	sumSq *SMA
+}
+
+// Constructor for Bollinger Bands over n closes, usually 20 and 2
+func NewBollinger(n int, k float64) *Bollinger {
+	return &Bollinger{k: k, sma: NewSMA(n), sumSq: NewSMA(n)}
+}
+
+// Add consumes a close and returns the bands once n closes have been seen
+func (b *Bollinger) Add(close float64) (Band, bool) {
+	mean, ok := b.sma.Add(close)
+	meanSq, _ := b.sumSq.Add(close * close)
+	if !ok {
+		return Band{}, false
+	}
+	dev := b.k * math.Sqrt(max(meanSq-mean*mean, 0))
+	return Band{Upper: mean + dev, Middle: mean, Lower: mean - dev}, true
+}
+
+func (b *Bollinger) Next(bar Bar) []float64 {
+	if v, ok := b.Add(bar.Close); ok {
+		return []float64{v.Upper, v.Middle, v.Lower}
+	}
+	return nil
+}
+
+func (b *Bollinger) Outputs() []string {
+	return []string{"upper", "middle", "lower"}
+}
+
+// ATR is Wilder's average true range over n bars
+type ATR struct {
+	n         int
+	count     int
+	prevClose float64
+	value     float64
+}
+
+// Constructor for an average true range over n bars
+func NewATR(n int) *ATR {
+	return &ATR{n: n}
+}
+
+// Add consumes a bar and returns the average once n bars have been seen.
+// The first bar's true range is its high minus its low.
+func (a *ATR) Add(b Bar) (float64, bool) {
+	tr := b.High - b.Low
+	if a.count > 0 {
+		tr = max(tr, math.Abs(b.High-a.prevClose), math.Abs(b.Low-a.prevClose))
+	}
+	a.prevClose = b.Close
+	a.count++
+
+	if a.count <= a.n {
+		a.value += tr / float64(a.n)
+		return a.value, a.count == a.n
+	}
+	a.value = (a.value*float64(a.n-1) + tr) / float64(a.n)
+	return a.value, true
+}
+
+func (a *ATR) Next(b Bar) []float64 {
+	if v, ok := a.Add(b); ok {
+		return []float64{v}
+	}
+	return nil
+}
+
+func (a *ATR) Outputs() []string {
+	return []string{"value"}
+}
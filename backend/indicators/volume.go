@@ -0,0 +1,80 @@
+package indicators
+
+// OBV is the on-balance volume: the running total of volume, added on up
+// closes and subtracted on down closes
+type OBV struct {
+	started   bool
+	prevClose float64
+	value     float64
+}
+
+// Constructor for on-balance volume, starting at 0
+func NewOBV() *OBV {
+	return &OBV{}
+}
+
+// Add consumes a bar and returns the running total
+func (o *OBV) Add(b Bar) float64 {
+	if o.started {
+		switch {
+		case b.Close > o.prevClose:
+			o.value += b.Volume
+		case b.Close < o.prevClose:
+			o.value -= b.Volume
+		}
+	}
+	o.started = true
+	o.prevClose = b.Close
+	return o.value
+}
+
+// Reset starts the running total again at 0 from the next bar
+func (o *OBV) Reset() {
+	*o = OBV{}
+}
+
+func (o *OBV) Next(b Bar) []float64 {
+	return []float64{o.Add(b)}
+}
+
+func (o *OBV) Outputs() []string {
+	return []string{"value"}
+}
+
+// VWAP is the volume weighted average of each bar's typical price, the
+// mean of its high, low and close, since the first bar or the last Reset
+type VWAP struct {
+	value  float64
+	volume float64
+}
+
+// Constructor for a volume weighted average price
+func NewVWAP() *VWAP {
+	return &VWAP{}
+}
+
+// Add consumes a bar and returns the average once some volume has traded
+func (v *VWAP) Add(b Bar) (float64, bool) {
+	v.value += (b.High + b.Low + b.Close) / 3 * b.Volume
+	v.volume += b.Volume
+	if v.volume == 0 {
+		return 0, false
+	}
+	return v.value / v.volume, true
+}
+
+// Reset anchors the average to the next bar
+func (v *VWAP) Reset() {
+	*v = VWAP{}
+}
+
+func (v *VWAP) Next(b Bar) []float64 {
+	if avg, ok := v.Add(b); ok {
+		return []float64{avg}
+	}
+	return nil
+}
+
+func (v *VWAP) Outputs() []string {
+	return []string{"value"}
+}
@@ -0,0 +1,22 @@
+package jobs
+
+import (
+	"context"
+	"isxportfolio-backend/market"
+)
+
+// CompanyProfileJob refreshes the listed company profiles once a week, on
+// Saturday night when the exchange is closed. Profiles scraped in the last
+// few days are skipped, so running on start only fills in what is missing.
+func CompanyProfileJob(profiles *market.ProfileScraper) Job {
+	return Job{
+		Name:        "company_profiles",
+		Description: "Scrape listed company profiles from the ISX website",
+		Schedule:    "0 3 * * 6",
+		When:        WhenAlways,
+		RunOnStart:  true,
+		Run: func(ctx context.Context) error {
+			return profiles.Run(ctx)
+		},
+	}
+}
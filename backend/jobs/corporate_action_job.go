@@ -0,0 +1,21 @@
+package jobs
+
+import (
+	"context"
+	"isxportfolio-backend/market"
+)
+
+// CorporateActionJob looks for dividends and capital changes in newly
+// stored news and queues them for admin review
+func CorporateActionJob(extractor *market.ActionExtractor) Job {
+	return Job{
+		Name:        "corporate_actions",
+		Description: "Extract corporate actions from market news for review",
+		Schedule:    "*/15 * * * *",
+		When:        WhenAlways,
+		RunOnStart:  true,
+		Run: func(ctx context.Context) error {
+			return extractor.Run(ctx)
+		},
+	}
+}
@@ -0,0 +1,114 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+// at parses a UTC time in "2006-01-02 15:04" format
+func at(t *testing.T, s string) time.Time {
+	t.Helper()
+	v, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestScheduleNext(t *testing.T) {
+	// 2025-01-01 is a Wednesday
+	tests := []struct {
+		spec string
+		from string
+		want []string
+	}{
+		{"*/15 * * * *", "2025-01-01 10:07", []string{"2025-01-01 10:15", "2025-01-01 10:30", "2025-01-01 10:45"}},
+		{"*/5 9-14 * * 0-4", "2025-01-01 14:57", []string{"2025-01-02 09:00", "2025-01-02 09:05"}},
+		{"0 9-14/2 * * *", "2025-01-01 09:00", []string{"2025-01-01 11:00", "2025-01-01 13:00", "2025-01-02 09:00"}},
+		{"30 15 * * 0-4", "2025-01-02 16:00", []string{"2025-01-05 15:30"}}, // skips Friday and Saturday
+		{"0 0 29 2 *", "2025-01-01 00:00", []string{"2028-02-29 00:00"}},
+		{"0 12 * * 7", "2025-01-01 00:00", []string{"2025-01-05 12:00"}}, // 7 is Sunday
+		{"5,10 8 * * *", "2025-01-01 08:05", []string{"2025-01-01 08:10", "2025-01-02 08:05"}},
+		{"0 0 31 * *", "2025-01-31 00:00", []string{"2025-03-31 00:00"}},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tt.spec, err)
+			continue
+		}
+		next := at(t, tt.from)
+		for _, want := range tt.want {
+			next = s.Next(next)
+			if !next.Equal(at(t, want)) {
+				t.Errorf("%q after %s: got %s, want %s", tt.spec, tt.from, next.Format("2006-01-02 15:04"), want)
+				break
+			}
+		}
+	}
+}
+
+func TestScheduleDayFields(t *testing.T) {
+	// January 2025: the 1st is a Wednesday, Mondays are the 6th, 13th, ...
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		// Only one day field restricted: it alone decides
+		{"0 9 * * 1", []string{"2025-01-06", "2025-01-13", "2025-01-20"}},
+		{"0 9 15 * *", []string{"2025-01-15", "2025-02-15", "2025-03-15"}},
+		// Both restricted: either one matching is enough
+		{"0 9 15 * 1", []string{"2025-01-06", "2025-01-13", "2025-01-15", "2025-01-20"}},
+		{"0 9 1,2 * 5", []string{"2025-01-02", "2025-01-03", "2025-01-10"}},
+		// A restricted month still applies to both
+		{"0 9 15 3 1", []string{"2025-03-03", "2025-03-10", "2025-03-15", "2025-03-17"}},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tt.spec, err)
+			continue
+		}
+		next := at(t, "2025-01-01 12:00")
+		for _, want := range tt.want {
+			next = s.Next(next)
+			if got := next.Format("2006-01-02"); got != want {
+				t.Errorf("%q: got %s, want %s", tt.spec, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"1-x * * * *",
+	} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestScheduleNextNever(t *testing.T) {
+	s, err := ParseSchedule("0 0 31 2 *")
+	if err != nil {
+		t.Fatalf("ParseSchedule: %v", err)
+	}
+	if next := s.Next(at(t, "2025-01-01 00:00")); !next.IsZero() {
+		t.Errorf("Next = %v, want the zero time for February 31st", next)
+	}
+}
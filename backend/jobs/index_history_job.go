@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"context"
+	"isxportfolio-backend/market"
+	"log"
+)
+
+// IndexHistoryJob stores the official ISX60 level after the close and then
+// rebuilds the computed indices from the day's prices, so it runs after
+// the price history job
+func IndexHistoryJob(indices *market.IndexScraper, calculator *market.IndexCalculator) Job {
+	return Job{
+		Name:        "index_history",
+		Description: "Store the ISX60 and recompute the sector indices",
+		Schedule:    "45 15 * * *",
+		When:        WhenAlways,
+		RunOnStart:  true,
+		Run: func(ctx context.Context) error {
+			// A failed scrape still leaves the computed indices worth updating
+			scrapeErr := indices.Update(ctx)
+			if scrapeErr != nil {
+				log.Printf("Error updating official index history: %v", scrapeErr)
+			}
+			if err := calculator.Recompute(); err != nil {
+				return err
+			}
+			return scrapeErr
+		},
+	}
+}
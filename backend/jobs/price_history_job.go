@@ -0,0 +1,22 @@
+package jobs
+
+import (
+	"context"
+	"isxportfolio-backend/market"
+)
+
+// PriceHistoryJob stores the day's trading results after the market
+// closes. Each run also collects sessions missed while the server was
+// down, so it runs on start and on non-trading days without harm.
+func PriceHistoryJob(prices *market.PriceScraper) Job {
+	return Job{
+		Name:        "price_history",
+		Description: "Store daily trading results from the ISX website",
+		Schedule:    "30 15 * * *",
+		When:        WhenAlways,
+		RunOnStart:  true,
+		Run: func(ctx context.Context) error {
+			return prices.Update(ctx)
+		},
+	}
+}
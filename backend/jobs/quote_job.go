@@ -0,0 +1,21 @@
+package jobs
+
+import (
+	"context"
+	"isxportfolio-backend/market"
+)
+
+// QuoteJob snapshots the live trading page every minute while the market
+// is open
+func QuoteJob(quotes *market.QuoteScraper) Job {
+	return Job{
+		Name:        "market_quotes",
+		Description: "Poll live quotes from the ISX trading page",
+		Schedule:    "* * * * *",
+		When:        WhenMarketOpen,
+		RunOnStart:  true,
+		Run: func(ctx context.Context) error {
+			return quotes.Poll(ctx)
+		},
+	}
+}
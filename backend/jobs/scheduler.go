@@ -0,0 +1,430 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"isxportfolio-backend/calendar"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Conditions a scheduled run must meet besides its cron expression
+const (
+	WhenAlways      = "always"
+	WhenTradingDays = "trading_days" // the exchange trades that day
+	WhenMarketOpen  = "market_open"  // the exchange is in session
+)
+
+// Outcomes of a job run
+const (
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+var (
+	// ErrJobNotFound is returned for an unknown job name
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobRunning is returned when a job is triggered while it is running
+	ErrJobRunning = errors.New("job is already running")
+)
+
+// Job is a unit of scheduled work
+type Job struct {
+	Name        string
+	Description string
+	// Schedule is a cron expression evaluated in the calendar's time zone
+	Schedule string
+	// When restricts scheduled runs to trading days or market hours
+	When string
+	// RunOnStart also runs the job when the scheduler starts, if When allows
+	RunOnStart bool
+	Run        func(ctx context.Context) error
+}
+
+// JobInfo describes a registered job and its state
+type JobInfo struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Schedule    string     `json:"schedule"`
+	When        string     `json:"when"`
+	Paused      bool       `json:"paused"`
+	Running     bool       `json:"running"`
+	NextRun     *time.Time `json:"next_run,omitempty"`
+	LastRun     *JobRun    `json:"last_run,omitempty"`
+}
+
+// JobRun is one recorded execution of a job
+type JobRun struct {
+	ID         int64      `json:"id"`
+	Job        string     `json:"job"`
+	Trigger    string     `json:"trigger"`
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMS int64      `json:"duration_ms"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// entry is a registered job and its runtime state
+type entry struct {
+	job      Job
+	schedule *Schedule
+	paused   bool
+	running  bool
+	nextRun  time.Time
+	wake     chan struct{} // signals the job loop that its state changed
+}
+
+// Scheduler runs registered jobs on their schedules and records every run
+// in the job_runs table
+type Scheduler struct {
+	db       *sql.DB
+	calendar *calendar.Calendar
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// Constructor for the scheduler
+func NewScheduler(db *sql.DB, cal *calendar.Calendar) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		db:       db,
+		calendar: cal,
+		entries:  make(map[string]*entry),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Register adds a job. Jobs must be registered before Start.
+func (s *Scheduler) Register(job Job) error {
+	schedule, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return err
+	}
+	switch job.When {
+	case "":
+		job.When = WhenAlways
+	case WhenAlways, WhenTradingDays, WhenMarketOpen:
+	default:
+		return fmt.Errorf("job %s: unknown condition %q", job.Name, job.When)
+	}
+
+	paused, err := s.loadPaused(job.Name)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.entries[job.Name]; exists {
+		return fmt.Errorf("job %s is already registered", job.Name)
+	}
+	s.entries[job.Name] = &entry{
+		job:      job,
+		schedule: schedule,
+		paused:   paused,
+		wake:     make(chan struct{}, 1),
+	}
+	log.Printf("Registered job %s (%s, %s)", job.Name, job.Schedule, job.When)
+	return nil
+}
+
+// Start runs every registered job's schedule in the background
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.entries {
+		s.wg.Add(1)
+		go s.loop(e)
+	}
+}
+
+// Stop stops scheduling new runs, cancels the runs in progress and waits
+// for them to return
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+	log.Println("Job scheduler stopped")
+}
+
+// loop waits for each scheduled time of a job and runs it
+func (s *Scheduler) loop(e *entry) {
+	defer s.wg.Done()
+
+	if e.job.RunOnStart {
+		s.runScheduled(e, "start")
+	}
+
+	for {
+		next := e.schedule.Next(time.Now().In(s.calendar.Location()))
+		s.mu.Lock()
+		e.nextRun = next
+		s.mu.Unlock()
+		if next.IsZero() {
+			log.Printf("Job %s has no future run times", e.job.Name)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-e.wake:
+			timer.Stop()
+		case <-timer.C:
+			s.runScheduled(e, "schedule")
+		}
+	}
+}
+
+// runScheduled runs a job unless it is paused, outside its calendar
+// condition or still running from before
+func (s *Scheduler) runScheduled(e *entry, trigger string) {
+	now := time.Now()
+	switch e.job.When {
+	case WhenTradingDays:
+		s.calendar.CheckCoverage(now)
+		if !s.calendar.IsTradingDay(now) {
+			return
+		}
+	case WhenMarketOpen:
+		s.calendar.CheckCoverage(now)
+		if !s.calendar.IsOpen(now) {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	if e.paused {
+		s.mu.Unlock()
+		return
+	}
+	if e.running {
+		s.mu.Unlock()
+		log.Printf("Job %s is still running, skipping its %s run", e.job.Name, trigger)
+		return
+	}
+	e.running = true
+	s.mu.Unlock()
+
+	s.execute(e, trigger)
+}
+
+// execute runs a job that has been marked running and records the outcome
+func (s *Scheduler) execute(e *entry, trigger string) {
+	defer func() {
+		s.mu.Lock()
+		e.running = false
+		s.mu.Unlock()
+	}()
+
+	run := JobRun{Job: e.job.Name, Trigger: trigger, Status: RunRunning, StartedAt: time.Now().UTC()}
+	id, err := s.insertRun(run)
+	if err != nil {
+		log.Printf("Error recording start of job %s: %v", e.job.Name, err)
+	}
+
+	log.Printf("Running job %s (%s)", e.job.Name, trigger)
+	runErr := safeRun(s.ctx, e.job.Run)
+
+	finished := time.Now().UTC()
+	run.FinishedAt = &finished
+	run.DurationMS = finished.Sub(run.StartedAt).Milliseconds()
+	run.Status = RunSucceeded
+	if runErr != nil {
+		run.Status = RunFailed
+		run.Error = runErr.Error()
+		log.Printf("Job %s failed after %dms: %v", e.job.Name, run.DurationMS, runErr)
+	} else {
+		log.Printf("Job %s finished in %dms", e.job.Name, run.DurationMS)
+	}
+
+	if id != 0 {
+		if err := s.finishRun(id, run); err != nil {
+			log.Printf("Error recording outcome of job %s: %v", e.job.Name, err)
+		}
+	}
+}
+
+// safeRun calls fn, turning a panic into an error
+func safeRun(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+// Trigger starts a run of the named job now, ignoring its schedule, pause
+// and calendar condition
+func (s *Scheduler) Trigger(name, trigger string) error {
+	s.mu.Lock()
+	e, ok := s.entries[name]
+	if !ok {
+		s.mu.Unlock()
+		return ErrJobNotFound
+	}
+	if e.running {
+		s.mu.Unlock()
+		return ErrJobRunning
+	}
+	e.running = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.execute(e, trigger)
+	}()
+	return nil
+}
+
+// Pause stops scheduled runs of a job until it is resumed. The setting is
+// kept across restarts.
+func (s *Scheduler) Pause(name string) error {
+	return s.setPaused(name, true)
+}
+
+// Resume re-enables scheduled runs of a paused job
+func (s *Scheduler) Resume(name string) error {
+	return s.setPaused(name, false)
+}
+
+func (s *Scheduler) setPaused(name string, paused bool) error {
+	s.mu.Lock()
+	e, ok := s.entries[name]
+	s.mu.Unlock()
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	if _, err := s.db.Exec(`
+		INSERT INTO job_settings (name, paused) VALUES (?, ?)
+		ON CONFLICT(name) DO UPDATE SET paused = excluded.paused, updated_at = CURRENT_TIMESTAMP`,
+		name, paused); err != nil {
+		return fmt.Errorf("error saving job state: %w", err)
+	}
+
+	s.mu.Lock()
+	e.paused = paused
+	s.mu.Unlock()
+
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+	log.Printf("Job %s paused: %v", name, paused)
+	return nil
+}
+
+// Jobs lists the registered jobs with their state and last run
+func (s *Scheduler) Jobs() ([]JobInfo, error) {
+	s.mu.Lock()
+	infos := make([]JobInfo, 0, len(s.entries))
+	for _, e := range s.entries {
+		info := JobInfo{
+			Name:        e.job.Name,
+			Description: e.job.Description,
+			Schedule:    e.job.Schedule,
+			When:        e.job.When,
+			Paused:      e.paused,
+			Running:     e.running,
+		}
+		if !e.nextRun.IsZero() && !e.paused {
+			next := e.nextRun
+			info.NextRun = &next
+		}
+		infos = append(infos, info)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	for i := range infos {
+		runs, err := s.Runs(infos[i].Name, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(runs) > 0 {
+			infos[i].LastRun = &runs[0]
+		}
+	}
+	return infos, nil
+}
+
+// Runs returns the most recent runs of the named job, newest first
+func (s *Scheduler) Runs(name string, limit int) ([]JobRun, error) {
+	s.mu.Lock()
+	_, ok := s.entries[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, job_name, trigger, status, started_at, finished_at, duration_ms, error
+		FROM job_runs
+		WHERE job_name = ?
+		ORDER BY id DESC
+		LIMIT ?`, name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying job runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []JobRun{}
+	for rows.Next() {
+		var run JobRun
+		var finished sql.NullTime
+		if err := rows.Scan(&run.ID, &run.Job, &run.Trigger, &run.Status, &run.StartedAt,
+			&finished, &run.DurationMS, &run.Error); err != nil {
+			return nil, fmt.Errorf("error scanning job run: %w", err)
+		}
+		if finished.Valid {
+			run.FinishedAt = &finished.Time
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func (s *Scheduler) loadPaused(name string) (bool, error) {
+	var paused bool
+	err := s.db.QueryRow("SELECT paused FROM job_settings WHERE name = ?", name).Scan(&paused)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading job state: %w", err)
+	}
+	return paused, nil
+}
+
+func (s *Scheduler) insertRun(run JobRun) (int64, error) {
+	res, err := s.db.Exec(`
+		INSERT INTO job_runs (job_name, trigger, status, started_at)
+		VALUES (?, ?, ?, ?)`, run.Job, run.Trigger, run.Status, run.StartedAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (s *Scheduler) finishRun(id int64, run JobRun) error {
+	_, err := s.db.Exec(`
+		UPDATE job_runs SET status = ?, finished_at = ?, duration_ms = ?, error = ?
+		WHERE id = ?`, run.Status, run.FinishedAt, run.DurationMS, run.Error, id)
+	return err
+}
@@ -16,9 +16,14 @@ package main
 
 // Importing the necessary packages
 import (
+	"isxportfolio-backend/calendar"
+	"isxportfolio-backend/classifier"
 	"isxportfolio-backend/config"
 	"isxportfolio-backend/handlers"
 	"isxportfolio-backend/jobs"
+	"isxportfolio-backend/market"
+	"isxportfolio-backend/registry"
+	"isxportfolio-backend/scraper"
 	"log"
 	"os"
 
@@ -44,6 +49,41 @@ func main() {
 
 	config.TestDatabaseWrite()
 
+	// Import market news saved by the old CSV based scraper
+	newsStore := scraper.NewNewsStore(config.DB)
+	if err := newsStore.ImportCSVOnce("/app/data/market_news.csv", "http://www.isx-iq.net"); err != nil {
+		log.Printf("Error importing legacy market news CSV: %v", err)
+	}
+	if err := newsStore.NormalizeStoredTitles(); err != nil {
+		log.Printf("Error normalizing stored news titles: %v", err)
+	}
+
+	// Tag stored news with the categories of the current rules
+	newsClassifier := classifier.LoadFromEnv()
+	if err := newsStore.Reclassify(newsClassifier.Classify); err != nil {
+		log.Printf("Error classifying stored news: %v", err)
+	}
+
+	// Market data store, with every listed company present before its
+	// profile is scraped, and a registry that also knows the companies
+	// stored since
+	companyRegistry := registry.LoadFromEnv()
+	marketStore := market.NewStore(config.DB)
+	if err := marketStore.SeedCompanies(companyRegistry); err != nil {
+		log.Printf("Error adding registry companies: %v", err)
+	}
+	if err := marketStore.RegisterCompanies(companyRegistry); err != nil {
+		log.Printf("Error adding stored companies to the registry: %v", err)
+	}
+
+	// Check stored news tickers against the listed companies
+	if err := newsStore.RetagTickers(companyRegistry); err != nil {
+		log.Printf("Error tagging stored news tickers: %v", err)
+	}
+	if err := marketStore.RecomputeAllAdjustments(); err != nil {
+		log.Printf("Error recomputing price adjustments: %v", err)
+	}
+
 	// Debug: Print environment variables
 	clientID := os.Getenv("GOOGLE_CLIENT_ID")
 	clientSecret := os.Getenv("GOOGLE_CLIENT_SECRET")
@@ -60,8 +100,9 @@ func main() {
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, Last-Event-ID, Range, If-None-Match, If-Modified-Since")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Disposition, Content-Range, ETag")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -71,10 +112,49 @@ func main() {
 		c.Next()
 	})
 
-	// Initialize and start the market news job
-	newsJob := jobs.NewMarketNewsJob()
-	newsJob.Start()
-	defer newsJob.Stop()
+	// Shared market news scraper used by the job and the API
+	newsScraper := scraper.NewMarketNewsScraper(newsStore, "/app/data/pdfs")
+	defer newsScraper.Close()
+	newsScraper.Classifier = newsClassifier
+	newsScraper.Registry = companyRegistry
+	newsBroker := scraper.NewNewsBroker(newsStore)
+	newsCoordinator := scraper.NewRunCoordinator(newsScraper, newsBroker)
+
+	// Trading calendar used by the job scheduler
+	marketCalendar, err := calendar.LoadFromEnv(config.DB)
+	if err != nil {
+		log.Fatalf("Error loading market calendar: %v", err)
+	}
+
+	// Schedule the background jobs
+	scheduler := jobs.NewScheduler(config.DB, marketCalendar)
+	if err := scheduler.Register(jobs.MarketNewsJob(newsCoordinator)); err != nil {
+		log.Fatalf("Error registering market news job: %v", err)
+	}
+	profileScraper := market.NewProfileScraper(marketStore, newsScraper.Fetcher, companyRegistry)
+	if err := scheduler.Register(jobs.CompanyProfileJob(profileScraper)); err != nil {
+		log.Fatalf("Error registering company profile job: %v", err)
+	}
+	priceScraper := market.NewPriceScraper(marketStore, newsScraper.Fetcher, marketCalendar)
+	if err := scheduler.Register(jobs.PriceHistoryJob(priceScraper)); err != nil {
+		log.Fatalf("Error registering price history job: %v", err)
+	}
+	quoteCache := market.NewQuoteCache()
+	quoteScraper := market.NewQuoteScraper(marketStore, newsScraper.Fetcher, quoteCache)
+	if err := scheduler.Register(jobs.QuoteJob(quoteScraper)); err != nil {
+		log.Fatalf("Error registering quote job: %v", err)
+	}
+	actionExtractor := market.NewActionExtractor(marketStore)
+	if err := scheduler.Register(jobs.CorporateActionJob(actionExtractor)); err != nil {
+		log.Fatalf("Error registering corporate action job: %v", err)
+	}
+	indexScraper := market.NewIndexScraper(marketStore, newsScraper.Fetcher, marketCalendar)
+	indexCalculator := market.NewIndexCalculator(marketStore, market.IndexWeightingFromEnv())
+	if err := scheduler.Register(jobs.IndexHistoryJob(indexScraper, indexCalculator)); err != nil {
+		log.Fatalf("Error registering index history job: %v", err)
+	}
+	scheduler.Start()
+	defer scheduler.Stop()
 
 	// Health check endpoint
 	r.GET("/health", handlers.HealthCheck)
@@ -88,13 +168,22 @@ func main() {
 	config.InitJWT()
 
 	// Setup routes
-	setupRoutes(r)
+	setupRoutes(r, newsStore, newsScraper, newsCoordinator, newsClassifier, newsBroker, marketCalendar, scheduler, marketStore, priceScraper, quoteCache)
 
 	// Start server
 	r.Run(":8000")
 }
 
-func setupRoutes(r *gin.Engine) {
+func setupRoutes(r *gin.Engine, newsStore *scraper.NewsStore, newsScraper *scraper.MarketNewsScraper, newsCoordinator *scraper.RunCoordinator, newsClassifier *classifier.Classifier, newsBroker *scraper.NewsBroker, marketCalendar *calendar.Calendar, scheduler *jobs.Scheduler, marketStore *market.Store, priceScraper *market.PriceScraper, quoteCache *market.QuoteCache) {
+	newsHandler := handlers.NewMarketNewsHandler(newsStore, newsCoordinator, newsClassifier, newsBroker)
+	calendarHandler := handlers.NewCalendarHandler(marketCalendar)
+	jobHandler := handlers.NewJobHandler(scheduler)
+	companyHandler := handlers.NewCompanyHandler(marketStore)
+	priceHandler := handlers.NewPriceHandler(marketStore, priceScraper)
+	quoteHandler := handlers.NewQuoteHandler(quoteCache)
+	actionHandler := handlers.NewActionHandler(marketStore)
+	indexHandler := handlers.NewIndexHandler(marketStore)
+
 	api := r.Group("/api")
 	{
 		// Your existing routes...
@@ -102,9 +191,51 @@ func setupRoutes(r *gin.Engine) {
 		// Market news routes
 		market := api.Group("/market")
 		{
-			newsHandler := handlers.NewMarketNewsHandler()
 			market.GET("/news", newsHandler.GetMarketNews)
+			market.GET("/news/categories", newsHandler.GetNewsCategories)
+			market.GET("/news/search", newsHandler.SearchMarketNews)
+			market.GET("/news/stream", newsHandler.StreamMarketNews)
 			market.POST("/news/refresh", newsHandler.RefreshMarketNews)
+			market.GET("/news/runs/:id", newsHandler.GetScraperRun)
+
+			attachmentHandler := handlers.NewAttachmentHandler(newsStore, newsScraper)
+			market.GET("/news/:id/attachments/:filename", attachmentHandler.GetNewsAttachment)
+
+			market.GET("/calendar", calendarHandler.GetCalendar)
+
+			market.GET("/companies", companyHandler.ListCompanies)
+			market.GET("/companies/:ticker", companyHandler.GetCompany)
+			market.GET("/tickers/:ticker/prices", priceHandler.GetPrices)
+			market.GET("/tickers/:ticker/candles", priceHandler.GetCandles)
+			market.GET("/tickers/:ticker/actions", actionHandler.GetTickerActions)
+			market.GET("/tickers/:ticker/indicators", priceHandler.GetIndicators)
+			market.GET("/quotes", quoteHandler.GetQuotes)
+			market.GET("/indices/:code/history", indexHandler.GetIndexHistory)
+		}
+
+		// Admin routes
+		admin := api.Group("/admin", handlers.RequireAdmin())
+		{
+			admin.PUT("/calendar/days/:date", calendarHandler.SetCalendarDay)
+			admin.DELETE("/calendar/days/:date", calendarHandler.DeleteCalendarDay)
+
+			admin.GET("/jobs", jobHandler.ListJobs)
+			admin.GET("/jobs/:name/runs", jobHandler.GetJobRuns)
+			admin.POST("/jobs/:name/pause", jobHandler.PauseJob)
+			admin.POST("/jobs/:name/resume", jobHandler.ResumeJob)
+			admin.POST("/jobs/:name/trigger", jobHandler.TriggerJob)
+
+			admin.POST("/news/backfill", newsHandler.BackfillMarketNews)
+			admin.POST("/news/runs/:id/cancel", newsHandler.CancelScraperRun)
+			admin.GET("/scraper/health", newsHandler.GetScraperHealth)
+
+			admin.POST("/prices/backfill", priceHandler.BackfillPrices)
+
+			admin.GET("/actions", actionHandler.ListActions)
+			admin.POST("/actions", actionHandler.CreateAction)
+			admin.PUT("/actions/:id", actionHandler.UpdateAction)
+			admin.POST("/actions/:id/approve", actionHandler.ApproveAction)
+			admin.POST("/actions/:id/reject", actionHandler.RejectAction)
 		}
 	}
 }
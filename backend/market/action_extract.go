@@ -0,0 +1,326 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"isxportfolio-backend/arabic"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// News categories whose items can announce a corporate action
+var actionCategories = []string{"dividend", "capital_increase"}
+
+// Items whose attachments are still being read are scanned once the text
+// is in, or after this long without it
+const actionScanWait = 48 * time.Hour
+
+// Maximum number of news items scanned per run
+const actionScanBatch = 500
+
+// Keywords, in normalized form, that introduce each kind of action
+var (
+	dividendKeywords = []string{"ارباح نقديه", "توزيع ارباح", "توزيع الارباح", "مقسوم", "cash dividend", "dividend"}
+	capitalKeywords  = []string{"زياده راس المال", "زياده راس مال", "زياده راسمال", "اسهم مجانيه", "رسمله", "اكتتاب",
+		"capital increase", "increase capital", "increase of capital", "bonus share", "bonus issue", "rights issue", "subscription"}
+	bonusKeywords   = []string{"اسهم مجانيه", "منح", "رسمله", "bonus", "capitaliz", "free shares"}
+	rightsKeywords  = []string{"اكتتاب", "rights", "subscription"}
+	splitKeywords   = []string{"تجزيه الاسهم", "تجزيه السهم", "تقسيم الاسهم", "تقسيم السهم", "stock split", "share split", "split"}
+	reverseKeywords = []string{"دمج الاسهم", "توحيد الاسهم", "reverse split", "reverse stock split", "share consolidation"}
+
+	exDateKeywords      = []string{"ex-date", "ex date", "ex-dividend", "تاريخ الاستحقاق"}
+	recordDateKeywords  = []string{"record date", "تاريخ التسجيل"}
+	paymentDateKeywords = []string{"payment date", "distribution date", "تاريخ التوزيع", "تاريخ الصرف", "اعتبارا من"}
+)
+
+var (
+	percentPattern   = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*%`)
+	perSharePattern  = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(?:دينار|iqd|dinars?)\s*(?:عراقي\s*)?(?:لكل سهم|للسهم|per share)`)
+	fromToPattern    = regexp.MustCompile(`(?:من|from)\s+(?:iqd\s*)?([\d,]+(?:\.\d+)?)\s*(?:مليار|مليون|billion|million)?\s*(?:دينار|iqd|dinars?)?\s*(?:عراقي\s*)?(?:الي|to)\s+(?:iqd\s*)?([\d,]+(?:\.\d+)?)`)
+	subscribePattern = regexp.MustCompile(`(?:بسعر|price of)\s*(?:iqd\s*)?(\d+(?:\.\d+)?)`)
+	datePattern      = regexp.MustCompile(`\d{4}-\d{1,2}-\d{1,2}|\d{1,2}[/-]\d{1,2}[/-]\d{4}`)
+)
+
+// Split ratio as in "2 for 1", "2-for-1" or "10 اسهم مقابل 1": shares held
+// after, shares held before
+var splitRatioPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(?:-\s*)?(?:new\s+)?(?:shares?\s+|اسهم\s+|سهم\s+)?(?:for|مقابل|لكل)\s*(?:-\s*)?(?:every\s+|each\s+)?(\d+(?:\.\d+)?)`)
+
+// Date layouts used in disclosures, day before month
+var actionDateLayouts = []string{"2006-1-2", "2/1/2006", "2-1-2006"}
+
+// How far past a keyword, in bytes of normalized text, its figure or date
+// is looked for
+const keywordWindow = 200
+
+// How far before a split keyword, in bytes, its ratio is looked for
+const splitRatioLead = 20
+
+// ExtractActions finds the corporate actions announced in the text of a
+// news item. The actions carry no ticker or news link; ISX shares have a
+// nominal value of 1 IQD, so a dividend of 10% of capital is 0.10 IQD per
+// share.
+func ExtractActions(text string) []CorporateAction {
+	norm := arabic.Normalize(strings.NewReplacer("٪", "%", "٫", ".").Replace(text))
+
+	var actions []CorporateAction
+	if a, ok := extractDividend(norm); ok {
+		actions = append(actions, a)
+	}
+	if a, ok := extractCapitalChange(norm); ok {
+		actions = append(actions, a)
+	}
+	if a, ok := extractSplit(norm); ok {
+		actions = append(actions, a)
+	}
+	for i := range actions {
+		actions[i].ExDate = dateAfter(norm, exDateKeywords)
+		actions[i].RecordDate = dateAfter(norm, recordDateKeywords)
+		if actions[i].Type == ActionDividend {
+			actions[i].PaymentDate = dateAfter(norm, paymentDateKeywords)
+		}
+	}
+	return actions
+}
+
+func extractDividend(norm string) (CorporateAction, bool) {
+	a := CorporateAction{Type: ActionDividend}
+	at := keywordIndex(norm, dividendKeywords)
+	if at < 0 {
+		return a, false
+	}
+	// Other per share amounts in a long disclosure, like a subscription
+	// price or last year's dividend, are not near the keyword
+	if m := perSharePattern.FindStringSubmatch(window(norm, at)); m != nil {
+		a.AmountPerShare = parsePrice(m[1])
+	} else if m := percentPattern.FindStringSubmatch(window(norm, at)); m != nil {
+		a.AmountPerShare = parsePrice(m[1]) / 100
+	}
+	return a, a.AmountPerShare > 0
+}
+
+func extractCapitalChange(norm string) (CorporateAction, bool) {
+	a := CorporateAction{Type: ActionCapitalIncrease}
+	at := keywordIndex(norm, capitalKeywords)
+	if at < 0 {
+		return a, false
+	}
+
+	bonus, rights := keywordIndex(norm, bonusKeywords) >= 0, keywordIndex(norm, rightsKeywords) >= 0
+	switch {
+	case bonus && !rights:
+		a.Type = ActionBonus
+	case rights && !bonus:
+		a.Type = ActionRightsIssue
+		if m := subscribePattern.FindStringSubmatch(norm); m != nil {
+			a.AmountPerShare = parsePrice(m[1])
+		}
+	}
+
+	// "from 100 billion to 125 billion" is more reliable than a percentage,
+	// which may describe something else
+	if m := fromToPattern.FindStringSubmatch(window(norm, at)); m != nil {
+		from, to := parsePrice(m[1]), parsePrice(m[2])
+		if from > 0 && to > from {
+			a.Ratio = to/from - 1
+		}
+	}
+	if a.Ratio == 0 {
+		if m := percentPattern.FindStringSubmatch(window(norm, at)); m != nil {
+			a.Ratio = parsePrice(m[1]) / 100
+		}
+	}
+	return a, a.Ratio > 0
+}
+
+// extractSplit finds a split, or a reverse split when a consolidation
+// keyword is present. The ratio is read from a "2 for 1" figure near the
+// keyword, which may come just before it as in "a 2-for-1 split", and is
+// turned around if it was given the other way.
+func extractSplit(norm string) (CorporateAction, bool) {
+	a := CorporateAction{Type: ActionSplit}
+	at := keywordIndex(norm, reverseKeywords)
+	if at >= 0 {
+		a.Type = ActionReverseSplit
+	} else if at = keywordIndex(norm, splitKeywords); at < 0 {
+		return a, false
+	}
+
+	m := splitRatioPattern.FindStringSubmatch(window(norm, max(0, at-splitRatioLead)))
+	if m == nil {
+		return a, false
+	}
+	after, before := parsePrice(m[1]), parsePrice(m[2])
+	if after <= 0 || before <= 0 || after == before {
+		return a, false
+	}
+	a.Ratio = after / before
+	if (a.Type == ActionSplit) != (a.Ratio > 1) {
+		a.Ratio = 1 / a.Ratio
+	}
+	return a, true
+}
+
+// keywordIndex returns the position of the first keyword found in norm, or -1
+func keywordIndex(norm string, keywords []string) int {
+	first := -1
+	for _, kw := range keywords {
+		if i := strings.Index(norm, kw); i >= 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+	return first
+}
+
+// window returns the text from position at up to keywordWindow bytes on
+func window(norm string, at int) string {
+	return norm[at:min(len(norm), at+keywordWindow)]
+}
+
+// dateAfter returns the first date following one of the keywords, or ""
+func dateAfter(norm string, keywords []string) string {
+	for _, kw := range keywords {
+		i := strings.Index(norm, kw)
+		if i < 0 {
+			continue
+		}
+		m := datePattern.FindString(window(norm, i+len(kw)))
+		if m == "" {
+			continue
+		}
+		for _, layout := range actionDateLayouts {
+			if t, err := time.Parse(layout, m); err == nil {
+				return t.Format(DateLayout)
+			}
+		}
+	}
+	return ""
+}
+
+// ActionExtractor fills the review queue with corporate actions found in
+// stored news items
+type ActionExtractor struct {
+	Store *Store
+}
+
+// Constructor for the corporate action extractor
+func NewActionExtractor(store *Store) *ActionExtractor {
+	return &ActionExtractor{Store: store}
+}
+
+// actionSource is a news item scanned for corporate actions
+type actionSource struct {
+	id        int64
+	twinID    int64
+	published string
+	tickers   []string
+	text      string
+}
+
+// Run scans the dividend and capital news items not scanned yet and queues
+// the actions they announce for review. Items about several companies are
+// left for an admin to enter, since their figures cannot be told apart.
+func (e *ActionExtractor) Run(ctx context.Context) error {
+	items, err := e.Store.unscannedActionNews()
+	if err != nil {
+		return err
+	}
+
+	queued := 0
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(item.tickers) == 1 {
+			for _, a := range ExtractActions(item.text) {
+				a.Ticker = item.tickers[0]
+				a.NewsID = item.id
+				a.AnnouncedDate = item.published
+				added, err := e.Store.addExtractedAction(&a, item.twinID)
+				if err != nil {
+					return err
+				}
+				if added {
+					queued++
+				}
+			}
+		}
+		if err := e.Store.markActionsScanned(item.id); err != nil {
+			return err
+		}
+	}
+
+	if queued > 0 {
+		log.Printf("Queued %d corporate actions for review from %d news items", queued, len(items))
+	}
+	return nil
+}
+
+// unscannedActionNews returns the action news items that have not been
+// scanned, once their attachment text is available
+func (s *Store) unscannedActionNews() ([]actionSource, error) {
+	cutoff := time.Now().UTC().Add(-actionScanWait).Format("2006-01-02 15:04:05")
+	args := []interface{}{}
+	for _, c := range actionCategories {
+		args = append(args, c)
+	}
+	args = append(args, cutoff, actionScanBatch)
+
+	rows, err := s.db.Query(`
+		SELECT n.id, COALESCE(n.twin_id, 0), COALESCE(DATE(n.published_at), ''), n.ticker,
+			COALESCE((SELECT GROUP_CONCAT(t.ticker) FROM news_tickers t WHERE t.news_id = n.id), ''),
+			n.title || ' ' || COALESCE((SELECT GROUP_CONCAT(a.text, ' ') FROM news_attachments a WHERE a.news_id = n.id), '')
+		FROM news_items n
+		WHERE n.actions_scanned = 0
+			AND n.category IN (?`+strings.Repeat(", ?", len(actionCategories)-1)+`)
+			AND (NOT EXISTS (SELECT 1 FROM news_attachments a WHERE a.news_id = n.id AND a.text_extracted = 0)
+				OR n.created_at < ?)
+		ORDER BY n.id
+		LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying news for corporate actions: %w", err)
+	}
+	defer rows.Close()
+
+	var items []actionSource
+	for rows.Next() {
+		var item actionSource
+		var ticker, tickers string
+		if err := rows.Scan(&item.id, &item.twinID, &item.published, &ticker, &tickers, &item.text); err != nil {
+			return nil, fmt.Errorf("error scanning news item: %w", err)
+		}
+		if tickers != "" {
+			item.tickers = strings.Split(tickers, ",")
+		} else if ticker != "" {
+			item.tickers = []string{ticker}
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// addExtractedAction queues an extracted action unless the other-language
+// version of its news item already produced the same one
+func (s *Store) addExtractedAction(a *CorporateAction, twinID int64) (bool, error) {
+	if twinID != 0 {
+		var exists bool
+		if err := s.db.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM corporate_actions WHERE news_id = ? AND ticker = ? AND type = ?)`,
+			twinID, strings.ToUpper(a.Ticker), a.Type).Scan(&exists); err != nil {
+			return false, fmt.Errorf("error checking corporate actions: %w", err)
+		}
+		if exists {
+			return false, nil
+		}
+	}
+	return s.AddAction(a)
+}
+
+// markActionsScanned records that a news item has been scanned
+func (s *Store) markActionsScanned(newsID int64) error {
+	if _, err := s.db.Exec("UPDATE news_items SET actions_scanned = 1 WHERE id = ?", newsID); err != nil {
+		return fmt.Errorf("error marking news item %d scanned: %w", newsID, err)
+	}
+	return nil
+}
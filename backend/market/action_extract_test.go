@@ -0,0 +1,114 @@
+package market
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractActions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []CorporateAction
+	}{
+		{
+			name: "dividend as a percentage of capital",
+			text: "قررت الهيئة العامة توزيع أرباح نقدية بنسبة ١٠٪ من رأس المال على المساهمين",
+			want: []CorporateAction{{Type: ActionDividend, AmountPerShare: 0.10}},
+		},
+		{
+			name: "dividend per share with dates",
+			text: "Cash dividend of 0.15 IQD per share. Ex-date: 2024-05-12, record date 14/05/2024, payment date 20/05/2024.",
+			want: []CorporateAction{{Type: ActionDividend, AmountPerShare: 0.15, ExDate: "2024-05-12", RecordDate: "2024-05-14", PaymentDate: "2024-05-20"}},
+		},
+		{
+			name: "dividend per share in Arabic",
+			text: "توزيع الأرباح بواقع ٠٫٠٥ دينار عراقي لكل سهم اعتبارا من 2/6/2024",
+			want: []CorporateAction{{Type: ActionDividend, AmountPerShare: 0.05, PaymentDate: "2024-06-02"}},
+		},
+		{
+			// A per share amount far from the keyword is something else
+			name: "per share amount outside the keyword window",
+			text: "توزيع أرباح نقدية بنسبة 8% من رأس المال. " + strings.Repeat("نص ", 100) + "سعر الاكتتاب 1 دينار للسهم",
+			want: []CorporateAction{{Type: ActionDividend, AmountPerShare: 0.08}},
+		},
+		{
+			name: "dividend without a figure",
+			text: "توزيع الأرباح خلال الاسبوع القادم",
+		},
+		{
+			name: "bonus issue from capital figures",
+			text: "زيادة رأس المال من 100 مليار دينار الى 125 مليار دينار عن طريق منح أسهم مجانية",
+			want: []CorporateAction{{Type: ActionBonus, Ratio: 0.25}},
+		},
+		{
+			name: "bonus issue from a percentage",
+			text: "Bonus issue: capital increase of 20% through capitalization of reserves",
+			want: []CorporateAction{{Type: ActionBonus, Ratio: 0.20}},
+		},
+		{
+			name: "rights issue with a subscription price",
+			text: "زيادة رأس المال بنسبة 50% عن طريق الاكتتاب بسعر 1 دينار للسهم",
+			want: []CorporateAction{{Type: ActionRightsIssue, Ratio: 0.5, AmountPerShare: 1}},
+		},
+		{
+			name: "bonus and rights together",
+			text: "Capital increase of 30% by bonus shares and a rights issue",
+			want: []CorporateAction{{Type: ActionCapitalIncrease, Ratio: 0.3}},
+		},
+		{
+			name: "ratio before the split keyword",
+			text: "The board approved a 2-for-1 stock split",
+			want: []CorporateAction{{Type: ActionSplit, Ratio: 2}},
+		},
+		{
+			name: "ratio after the split keyword in Arabic",
+			text: "تجزئة الأسهم بواقع ١٠ اسهم مقابل ١",
+			want: []CorporateAction{{Type: ActionSplit, Ratio: 10}},
+		},
+		{
+			name: "split ratio given the other way",
+			text: "share split: 1 for 4",
+			want: []CorporateAction{{Type: ActionSplit, Ratio: 4}},
+		},
+		{
+			name: "reverse split",
+			text: "reverse split of 1 for every 10 shares",
+			want: []CorporateAction{{Type: ActionReverseSplit, Ratio: 0.1}},
+		},
+		{
+			name: "reverse split ratio given the other way",
+			text: "دمج الأسهم بنسبة 5 مقابل 1",
+			want: []CorporateAction{{Type: ActionReverseSplit, Ratio: 0.2}},
+		},
+		{
+			name: "split without a ratio",
+			text: "stock split under study",
+		},
+		{
+			name: "split of one for one",
+			text: "stock split 1 for 1",
+		},
+		{
+			name: "unrelated news",
+			text: "ايقاف التداول على اسهم الشركة لعدم تقديم البيانات المالية",
+		},
+	}
+	for _, tt := range tests {
+		got := ExtractActions(tt.text)
+		for i := range got {
+			got[i].Ratio = round6(got[i].Ratio)
+			got[i].AmountPerShare = round6(got[i].AmountPerShare)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// round6 drops floating point noise from computed figures
+func round6(f float64) float64 {
+	return math.Round(f*1e6) / 1e6
+}
@@ -0,0 +1,284 @@
+package market
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Types of corporate action
+const (
+	ActionDividend        = "dividend"         // cash dividend
+	ActionBonus           = "bonus"            // free shares from capitalized reserves
+	ActionRightsIssue     = "rights_issue"     // new shares offered to shareholders for subscription
+	ActionCapitalIncrease = "capital_increase" // capital increase by an unstated method
+	ActionSplit           = "split"            // each share divided into several
+	ActionReverseSplit    = "reverse_split"    // several shares merged into one
+)
+
+// Review states of a corporate action. Only approved actions are served
+// publicly and used to adjust prices; changing an action recomputes the
+// adjustment factors of its ticker.
+const (
+	ActionPending  = "pending"
+	ActionApproved = "approved"
+	ActionRejected = "rejected"
+)
+
+// Where a corporate action came from
+const (
+	SourceExtracted = "extracted"
+	SourceManual    = "manual"
+)
+
+// CorporateAction is a dividend or change to a company's share capital
+type CorporateAction struct {
+	ID     int64  `json:"id"`
+	Ticker string `json:"ticker"`
+	Type   string `json:"type"`
+	// Ratio is the number of new shares per share held, e.g. 0.25 for a 25%
+	// bonus issue. For splits it is the number of shares held after per
+	// share held before: 2 for a 2-for-1 split, 0.1 for a 1-for-10 reverse
+	// split. It is 0 for dividends.
+	Ratio float64 `json:"ratio"`
+	// AmountPerShare is the cash dividend, or the subscription price of a
+	// rights issue, in IQD
+	AmountPerShare float64    `json:"amount_per_share"`
+	AnnouncedDate  string     `json:"announced_date,omitempty"` // YYYY-MM-DD
+	ExDate         string     `json:"ex_date,omitempty"`
+	RecordDate     string     `json:"record_date,omitempty"`
+	PaymentDate    string     `json:"payment_date,omitempty"`
+	NewsID         int64      `json:"news_id,omitempty"`
+	NewsTitle      string     `json:"news_title,omitempty"`
+	NewsLink       string     `json:"news_link,omitempty"`
+	Status         string     `json:"status"`
+	Source         string     `json:"source"`
+	Notes          string     `json:"notes,omitempty"`
+	ReviewedBy     string     `json:"reviewed_by,omitempty"`
+	ReviewedAt     *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// ActionFilter holds the criteria for listing corporate actions
+type ActionFilter struct {
+	Ticker string
+	Type   string
+	Status string
+}
+
+// ValidActionType reports whether t is a known corporate action type
+func ValidActionType(t string) bool {
+	switch t {
+	case ActionDividend, ActionBonus, ActionRightsIssue, ActionCapitalIncrease, ActionSplit, ActionReverseSplit:
+		return true
+	}
+	return false
+}
+
+// ErrInvalidActionType is returned for an unknown corporate action type
+var ErrInvalidActionType = errors.New("type must be dividend, bonus, rights_issue, capital_increase, split or reverse_split")
+
+// Validate checks the fields an admin can set on an action
+func (a *CorporateAction) Validate() error {
+	if a.Ticker == "" {
+		return errors.New("ticker is required")
+	}
+	if !ValidActionType(a.Type) {
+		return ErrInvalidActionType
+	}
+	if a.Ratio < 0 || a.AmountPerShare < 0 {
+		return errors.New("ratio and amount_per_share must not be negative")
+	}
+	if a.Type == ActionDividend && a.AmountPerShare == 0 {
+		return errors.New("a dividend needs amount_per_share")
+	}
+	if a.Type != ActionDividend && a.Ratio == 0 {
+		return errors.New("a capital change needs a ratio")
+	}
+	if a.Type == ActionSplit && a.Ratio <= 1 {
+		return errors.New("a split needs a ratio above 1")
+	}
+	if a.Type == ActionReverseSplit && a.Ratio >= 1 {
+		return errors.New("a reverse split needs a ratio below 1")
+	}
+	for _, d := range []string{a.AnnouncedDate, a.ExDate, a.RecordDate, a.PaymentDate} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse(DateLayout, d); err != nil {
+			return errors.New("dates must be in YYYY-MM-DD format")
+		}
+	}
+	return nil
+}
+
+// AddAction stores a new corporate action and sets its ID. An extracted
+// action that was already recorded for the same news item is skipped and
+// reported with a false result.
+func (s *Store) AddAction(a *CorporateAction) (bool, error) {
+	a.Ticker = strings.ToUpper(a.Ticker)
+	if a.Status == "" {
+		a.Status = ActionPending
+	}
+	if a.Source == "" {
+		a.Source = SourceExtracted
+	}
+	var newsID interface{}
+	if a.NewsID != 0 {
+		newsID = a.NewsID
+	}
+
+	res, err := s.db.Exec(`
+		INSERT INTO corporate_actions (ticker, type, ratio, amount_per_share, announced_date, ex_date,
+			record_date, payment_date, news_id, status, source, notes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(news_id, ticker, type) DO NOTHING`,
+		a.Ticker, a.Type, a.Ratio, a.AmountPerShare, a.AnnouncedDate, a.ExDate,
+		a.RecordDate, a.PaymentDate, newsID, a.Status, a.Source, a.Notes)
+	if err != nil {
+		return false, fmt.Errorf("error saving corporate action of %s: %w", a.Ticker, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	a.ID, _ = res.LastInsertId()
+	if a.Status == ActionApproved {
+		s.recomputeAdjustments(a.Ticker)
+	}
+	return true, nil
+}
+
+// UpdateAction replaces the editable fields of a stored action
+func (s *Store) UpdateAction(a *CorporateAction) error {
+	oldTicker, err := s.actionTicker(a.ID)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.Exec(`
+		UPDATE corporate_actions SET
+			ticker = ?, type = ?, ratio = ?, amount_per_share = ?, announced_date = ?,
+			ex_date = ?, record_date = ?, payment_date = ?, notes = ?,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		strings.ToUpper(a.Ticker), a.Type, a.Ratio, a.AmountPerShare, a.AnnouncedDate,
+		a.ExDate, a.RecordDate, a.PaymentDate, a.Notes, a.ID)
+	if err != nil {
+		return fmt.Errorf("error updating corporate action %d: %w", a.ID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	s.recomputeAdjustments(oldTicker, strings.ToUpper(a.Ticker))
+	return nil
+}
+
+// ReviewAction sets the review state of an action and who decided it
+func (s *Store) ReviewAction(id int64, status, reviewer string) error {
+	res, err := s.db.Exec(`
+		UPDATE corporate_actions SET status = ?, reviewed_by = ?, reviewed_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`, status, reviewer, id)
+	if err != nil {
+		return fmt.Errorf("error reviewing corporate action %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	ticker, err := s.actionTicker(id)
+	if err != nil {
+		return err
+	}
+	s.recomputeAdjustments(ticker)
+	return nil
+}
+
+// actionTicker returns the ticker of a stored action
+func (s *Store) actionTicker(id int64) (string, error) {
+	var ticker string
+	err := s.db.QueryRow("SELECT ticker FROM corporate_actions WHERE id = ?", id).Scan(&ticker)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("error querying corporate action %d: %w", id, err)
+	}
+	return ticker, nil
+}
+
+// Action returns the stored action with the given ID
+func (s *Store) Action(id int64) (*CorporateAction, error) {
+	row := s.db.QueryRow(actionColumns+" WHERE a.id = ?", id)
+	a, err := scanAction(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return a, err
+}
+
+// Actions lists the stored actions matching the filter, most recent first
+func (s *Store) Actions(filter ActionFilter) ([]CorporateAction, error) {
+	var where []string
+	var args []interface{}
+	if filter.Ticker != "" {
+		where = append(where, "a.ticker = ?")
+		args = append(args, strings.ToUpper(filter.Ticker))
+	}
+	if filter.Type != "" {
+		where = append(where, "a.type = ?")
+		args = append(args, filter.Type)
+	}
+	if filter.Status != "" {
+		where = append(where, "a.status = ?")
+		args = append(args, filter.Status)
+	}
+	whereSQL := ""
+	if len(where) > 0 {
+		whereSQL = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	rows, err := s.db.Query(actionColumns+whereSQL+`
+		ORDER BY COALESCE(NULLIF(a.ex_date, ''), NULLIF(a.announced_date, ''), DATE(a.created_at)) DESC, a.id DESC`,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying corporate actions: %w", err)
+	}
+	defer rows.Close()
+
+	actions := []CorporateAction{}
+	for rows.Next() {
+		a, err := scanAction(rows)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, *a)
+	}
+	return actions, rows.Err()
+}
+
+// Columns read by scanAction
+const actionColumns = `
+	SELECT a.id, a.ticker, a.type, a.ratio, a.amount_per_share, a.announced_date, a.ex_date,
+		a.record_date, a.payment_date, COALESCE(a.news_id, 0), COALESCE(n.title, ''), COALESCE(n.link, ''),
+		a.status, a.source, a.notes, a.reviewed_by, a.reviewed_at, a.created_at
+	FROM corporate_actions a
+	LEFT JOIN news_items n ON n.id = a.news_id`
+
+func scanAction(row rowScanner) (*CorporateAction, error) {
+	var a CorporateAction
+	var reviewed sql.NullTime
+	if err := row.Scan(&a.ID, &a.Ticker, &a.Type, &a.Ratio, &a.AmountPerShare, &a.AnnouncedDate, &a.ExDate,
+		&a.RecordDate, &a.PaymentDate, &a.NewsID, &a.NewsTitle, &a.NewsLink,
+		&a.Status, &a.Source, &a.Notes, &a.ReviewedBy, &reviewed, &a.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error scanning corporate action: %w", err)
+	}
+	if reviewed.Valid {
+		a.ReviewedAt = &reviewed.Time
+	}
+	return &a, nil
+}
@@ -0,0 +1,223 @@
+package market
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+)
+
+// Adjustment is the factor applied to a ticker's prices before the ex-date
+// of a corporate action so they compare with prices after it
+type Adjustment struct {
+	ActionID int64   `json:"action_id"`
+	ExDate   string  `json:"ex_date"`
+	Factor   float64 `json:"factor"`
+}
+
+// actionFactor returns the price factor of an action given the last close
+// before its ex-date. Bonus shares divide the price by 1 + ratio; a rights
+// issue moves it to the theoretical ex-rights price; splits and reverse
+// splits divide it by the ratio. Dividends and capital increases of
+// unknown method are not adjusted.
+func actionFactor(a CorporateAction, prevClose float64) float64 {
+	switch a.Type {
+	case ActionSplit, ActionReverseSplit:
+		if a.Ratio <= 0 {
+			return 1
+		}
+		return 1 / a.Ratio
+	case ActionBonus:
+		return 1 / (1 + a.Ratio)
+	case ActionRightsIssue:
+		// Without a close the new shares are treated like a bonus issue
+		if prevClose <= 0 {
+			return 1 / (1 + a.Ratio)
+		}
+		// Rights priced at or above the market are worth nothing
+		if a.AmountPerShare >= prevClose {
+			return 1
+		}
+		exRights := (prevClose + a.Ratio*a.AmountPerShare) / (1 + a.Ratio)
+		return exRights / prevClose
+	}
+	return 1
+}
+
+// RecomputeAdjustments rebuilds a ticker's adjustment factors from its
+// approved corporate actions. An action without an ex-date takes effect at
+// the first stored session after its record date; actions with neither
+// are skipped.
+func (s *Store) RecomputeAdjustments(ticker string) error {
+	ticker = strings.ToUpper(ticker)
+	actions, err := s.Actions(ActionFilter{Ticker: ticker, Status: ActionApproved})
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM price_adjustments WHERE ticker = ?", ticker); err != nil {
+		return fmt.Errorf("error clearing adjustments of %s: %w", ticker, err)
+	}
+
+	for _, a := range actions {
+		switch a.Type {
+		case ActionBonus, ActionRightsIssue, ActionSplit, ActionReverseSplit:
+		default:
+			continue
+		}
+		exDate := a.ExDate
+		if exDate == "" && a.RecordDate != "" {
+			if err := tx.QueryRow(`
+				SELECT date FROM price_history WHERE ticker = ? AND date > ? ORDER BY date LIMIT 1`,
+				ticker, a.RecordDate).Scan(&exDate); err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("error finding ex-date of action %d: %w", a.ID, err)
+			}
+		}
+		if exDate == "" {
+			continue
+		}
+
+		var prevClose float64
+		if err := tx.QueryRow(`
+			SELECT close FROM price_history WHERE ticker = ? AND date < ? ORDER BY date DESC LIMIT 1`,
+			ticker, exDate).Scan(&prevClose); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("error finding close before action %d: %w", a.ID, err)
+		}
+
+		factor := actionFactor(a, prevClose)
+		if factor == 1 {
+			continue
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO price_adjustments (ticker, action_id, ex_date, factor) VALUES (?, ?, ?, ?)`,
+			ticker, a.ID, exDate, factor); err != nil {
+			return fmt.Errorf("error saving adjustment of action %d: %w", a.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing adjustments of %s: %w", ticker, err)
+	}
+	return nil
+}
+
+// RecomputeAllAdjustments rebuilds the adjustment factors of every ticker
+// with corporate actions
+func (s *Store) RecomputeAllAdjustments() error {
+	rows, err := s.db.Query(`
+		SELECT ticker FROM corporate_actions
+		UNION SELECT ticker FROM price_adjustments`)
+	if err != nil {
+		return fmt.Errorf("error querying tickers with actions: %w", err)
+	}
+	var tickers []string
+	for rows.Next() {
+		var ticker string
+		if err := rows.Scan(&ticker); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning ticker: %w", err)
+		}
+		tickers = append(tickers, ticker)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error querying tickers with actions: %w", err)
+	}
+
+	for _, ticker := range tickers {
+		if err := s.RecomputeAdjustments(ticker); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recomputeAdjustments rebuilds the factors of tickers whose actions
+// changed. Failures are logged; the factors are rebuilt again on restart.
+func (s *Store) recomputeAdjustments(tickers ...string) {
+	seen := make(map[string]bool)
+	for _, ticker := range tickers {
+		if ticker == "" || seen[ticker] {
+			continue
+		}
+		seen[ticker] = true
+		if err := s.RecomputeAdjustments(ticker); err != nil {
+			log.Printf("Error recomputing price adjustments of %s: %v", ticker, err)
+		}
+	}
+}
+
+// Adjustments returns a ticker's adjustment factors, oldest first
+func (s *Store) Adjustments(ticker string) ([]Adjustment, error) {
+	rows, err := s.db.Query(`
+		SELECT action_id, ex_date, factor FROM price_adjustments
+		WHERE ticker = ? ORDER BY ex_date, action_id`, strings.ToUpper(ticker))
+	if err != nil {
+		return nil, fmt.Errorf("error querying adjustments: %w", err)
+	}
+	defer rows.Close()
+
+	adjustments := []Adjustment{}
+	for rows.Next() {
+		var a Adjustment
+		if err := rows.Scan(&a.ActionID, &a.ExDate, &a.Factor); err != nil {
+			return nil, fmt.Errorf("error scanning adjustment: %w", err)
+		}
+		adjustments = append(adjustments, a)
+	}
+	return adjustments, rows.Err()
+}
+
+// Adjust implements PriceAdjuster with the stored factors
+func (s *Store) Adjust(ticker string, prices []DailyPrice) ([]DailyPrice, error) {
+	adjustments, err := s.Adjustments(ticker)
+	if err != nil {
+		return nil, err
+	}
+	return adjustPrices(prices, adjustments), nil
+}
+
+// adjustPrices applies adjustments, oldest first, to prices. Each price is
+// multiplied by the factors of every later ex-date and its volume divided
+// by them, so the traded value is unchanged.
+func adjustPrices(prices []DailyPrice, adjustments []Adjustment) []DailyPrice {
+	if len(adjustments) == 0 {
+		return prices
+	}
+
+	// cumulative[i] is the product of the factors from adjustment i on
+	cumulative := make([]float64, len(adjustments)+1)
+	cumulative[len(adjustments)] = 1
+	for i := len(adjustments) - 1; i >= 0; i-- {
+		cumulative[i] = cumulative[i+1] * adjustments[i].Factor
+	}
+
+	adjusted := make([]DailyPrice, len(prices))
+	next := 0
+	for i, p := range prices {
+		for next < len(adjustments) && adjustments[next].ExDate <= p.Date {
+			next++
+		}
+		f := cumulative[next]
+		p.Open = roundPrice(p.Open * f)
+		p.High = roundPrice(p.High * f)
+		p.Low = roundPrice(p.Low * f)
+		p.Close = roundPrice(p.Close * f)
+		p.Volume = int64(float64(p.Volume)/f + 0.5)
+		adjusted[i] = p
+	}
+	return adjusted
+}
+
+// roundPrice drops the floating point noise of adjusted prices
+func roundPrice(f float64) float64 {
+	return math.Round(f*1e6) / 1e6
+}
@@ -0,0 +1,84 @@
+package market
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestActionFactor(t *testing.T) {
+	tests := []struct {
+		name      string
+		action    CorporateAction
+		prevClose float64
+		want      float64
+	}{
+		{"25% bonus issue", CorporateAction{Type: ActionBonus, Ratio: 0.25}, 2, 0.8},
+		{"100% bonus issue", CorporateAction{Type: ActionBonus, Ratio: 1}, 0, 0.5},
+		// 1 new share per 2 held at 1 IQD with a 2 IQD close: the
+		// theoretical ex-rights price is (2 + 0.5 × 1) / 1.5
+		{"rights issue below the market", CorporateAction{Type: ActionRightsIssue, Ratio: 0.5, AmountPerShare: 1}, 2, 2.5 / 1.5 / 2},
+		{"rights issue at par", CorporateAction{Type: ActionRightsIssue, Ratio: 1, AmountPerShare: 1}, 1.6, 1.3 / 1.6},
+		{"rights issue above the market", CorporateAction{Type: ActionRightsIssue, Ratio: 0.5, AmountPerShare: 3}, 2, 1},
+		{"rights issue at the market", CorporateAction{Type: ActionRightsIssue, Ratio: 0.5, AmountPerShare: 2}, 2, 1},
+		{"rights issue without a close", CorporateAction{Type: ActionRightsIssue, Ratio: 0.5, AmountPerShare: 1}, 0, 1 / 1.5},
+		{"2-for-1 split", CorporateAction{Type: ActionSplit, Ratio: 2}, 4, 0.5},
+		{"1-for-10 reverse split", CorporateAction{Type: ActionReverseSplit, Ratio: 0.1}, 0.2, 10},
+		{"split without a ratio", CorporateAction{Type: ActionSplit}, 4, 1},
+		{"cash dividend", CorporateAction{Type: ActionDividend, AmountPerShare: 0.1}, 2, 1},
+		{"capital increase of unknown method", CorporateAction{Type: ActionCapitalIncrease, Ratio: 0.3}, 2, 1},
+	}
+	for _, tt := range tests {
+		if got := actionFactor(tt.action, tt.prevClose); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: factor = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAdjustPrices(t *testing.T) {
+	prices := []DailyPrice{
+		{Date: "2025-01-15", Open: 10, High: 11, Low: 9, Close: 10, Volume: 100, Value: 1000},
+		{Date: "2025-01-30", Open: 10, High: 10, Low: 10, Close: 10, Volume: 80, Value: 800},
+		{Date: "2025-02-02", Open: 8, High: 8.5, Low: 7.5, Close: 8, Volume: 125, Value: 1000}, // bonus ex-date
+		{Date: "2025-03-02", Open: 4, High: 4, Low: 4, Close: 4, Volume: 250, Value: 1000},     // split ex-date
+		{Date: "2025-03-05", Open: 4.2, High: 4.4, Low: 4, Close: 4.2, Volume: 300, Value: 1260},
+	}
+	adjustments := []Adjustment{
+		{ActionID: 1, ExDate: "2025-02-02", Factor: 0.8}, // 25% bonus
+		{ActionID: 2, ExDate: "2025-03-02", Factor: 0.5}, // 2-for-1 split
+	}
+	want := []DailyPrice{
+		{Date: "2025-01-15", Open: 4, High: 4.4, Low: 3.6, Close: 4, Volume: 250, Value: 1000},
+		{Date: "2025-01-30", Open: 4, High: 4, Low: 4, Close: 4, Volume: 200, Value: 800},
+		{Date: "2025-02-02", Open: 4, High: 4.25, Low: 3.75, Close: 4, Volume: 250, Value: 1000},
+		{Date: "2025-03-02", Open: 4, High: 4, Low: 4, Close: 4, Volume: 250, Value: 1000},
+		{Date: "2025-03-05", Open: 4.2, High: 4.4, Low: 4, Close: 4.2, Volume: 300, Value: 1260},
+	}
+
+	got := adjustPrices(prices, adjustments)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("adjusted prices:\n got %+v\nwant %+v", got, want)
+	}
+	if prices[0].Close != 10 {
+		t.Error("adjustPrices changed its input")
+	}
+
+	if got := adjustPrices(prices, nil); !reflect.DeepEqual(got, prices) {
+		t.Errorf("prices without adjustments changed: %+v", got)
+	}
+}
+
+func TestAdjustPricesReverseSplit(t *testing.T) {
+	prices := []DailyPrice{
+		{Date: "2025-05-01", Open: 0.2, High: 0.21, Low: 0.19, Close: 0.2, Volume: 100000},
+		{Date: "2025-05-04", Open: 2, High: 2.1, Low: 1.9, Close: 2, Volume: 10000},
+	}
+	adjustments := []Adjustment{{ActionID: 1, ExDate: "2025-05-04", Factor: actionFactor(CorporateAction{Type: ActionReverseSplit, Ratio: 0.1}, 0.2)}}
+	want := []DailyPrice{
+		{Date: "2025-05-01", Open: 2, High: 2.1, Low: 1.9, Close: 2, Volume: 10000},
+		{Date: "2025-05-04", Open: 2, High: 2.1, Low: 1.9, Close: 2, Volume: 10000},
+	}
+	if got := adjustPrices(prices, adjustments); !reflect.DeepEqual(got, want) {
+		t.Errorf("adjusted prices:\n got %+v\nwant %+v", got, want)
+	}
+}
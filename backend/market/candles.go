@@ -0,0 +1,147 @@
+package market
+
+import (
+	"errors"
+	"time"
+)
+
+// Candle intervals
+const (
+	IntervalDay   = "1d"
+	IntervalWeek  = "1w"
+	IntervalMonth = "1M"
+)
+
+// ErrInvalidInterval is returned for an interval other than 1d, 1w or 1M
+var ErrInvalidInterval = errors.New("interval must be 1d, 1w or 1M")
+
+// Candle is one bar of a ticker's prices over a day, week or month
+type Candle struct {
+	Time   string  `json:"time"` // first day of the period, YYYY-MM-DD
+	Open   float64 `json:"open"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Close  float64 `json:"close"`
+	Volume int64   `json:"volume"`
+	Value  float64 `json:"value"`
+	Trades int64   `json:"trades"`
+}
+
+// CandleColumns holds candles column by column, the compact form served to
+// charts
+type CandleColumns struct {
+	Time   []string  `json:"t"`
+	Open   []float64 `json:"o"`
+	High   []float64 `json:"h"`
+	Low    []float64 `json:"l"`
+	Close  []float64 `json:"c"`
+	Volume []int64   `json:"v"`
+}
+
+// PriceAdjuster rewrites a ticker's daily prices to account for corporate
+// actions. Prices are given and returned oldest first.
+type PriceAdjuster interface {
+	Adjust(ticker string, prices []DailyPrice) ([]DailyPrice, error)
+}
+
+// Candles returns a ticker's bars between from and to, oldest first. Weekly
+// and monthly bars are built from daily prices; from is moved back to the
+// start of its period so the first bar is complete. Prices go through
+// adjuster first when it is not nil.
+func (s *Store) Candles(ticker, interval string, from, to time.Time, adjuster PriceAdjuster) ([]Candle, error) {
+	if !ValidInterval(interval) {
+		return nil, ErrInvalidInterval
+	}
+	if !from.IsZero() {
+		from = periodStart(from, interval)
+	}
+
+	prices, err := s.Prices(ticker, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if adjuster != nil {
+		if prices, err = adjuster.Adjust(ticker, prices); err != nil {
+			return nil, err
+		}
+	}
+	return AggregateCandles(prices, interval), nil
+}
+
+// ValidInterval reports whether interval is a supported candle interval
+func ValidInterval(interval string) bool {
+	switch interval {
+	case IntervalDay, IntervalWeek, IntervalMonth:
+		return true
+	}
+	return false
+}
+
+// AggregateCandles groups daily prices, oldest first, into bars of the
+// given interval. Weeks are ISX trading weeks, which run Sunday to Thursday.
+func AggregateCandles(prices []DailyPrice, interval string) []Candle {
+	candles := []Candle{}
+	for _, p := range prices {
+		day, err := time.Parse(DateLayout, p.Date)
+		if err != nil {
+			continue
+		}
+		start := periodStart(day, interval).Format(DateLayout)
+
+		if n := len(candles); n > 0 && candles[n-1].Time == start {
+			c := &candles[n-1]
+			c.High = max(c.High, p.High)
+			c.Low = min(c.Low, p.Low)
+			c.Close = p.Close
+			c.Volume += p.Volume
+			c.Value += p.Value
+			c.Trades += p.Trades
+			continue
+		}
+		candles = append(candles, Candle{
+			Time:   start,
+			Open:   p.Open,
+			High:   p.High,
+			Low:    p.Low,
+			Close:  p.Close,
+			Volume: p.Volume,
+			Value:  p.Value,
+			Trades: p.Trades,
+		})
+	}
+	return candles
+}
+
+// periodStart returns the first day of the interval's period containing day
+func periodStart(day time.Time, interval string) time.Time {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case IntervalWeek:
+		return day.AddDate(0, 0, -int(day.Weekday()))
+	case IntervalMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	default:
+		return day
+	}
+}
+
+// Columns converts candles to their columnar form
+func Columns(candles []Candle) CandleColumns {
+	cols := CandleColumns{
+		Time:   make([]string, len(candles)),
+		Open:   make([]float64, len(candles)),
+		High:   make([]float64, len(candles)),
+		Low:    make([]float64, len(candles)),
+		Close:  make([]float64, len(candles)),
+		Volume: make([]int64, len(candles)),
+	}
+	for i, c := range candles {
+		cols.Time[i] = c.Time
+		cols.Open[i] = c.Open
+		cols.High[i] = c.High
+		cols.Low[i] = c.Low
+		cols.Close[i] = c.Close
+		cols.Volume[i] = c.Volume
+	}
+	return cols
+}
@@ -0,0 +1,116 @@
+package market
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// dailyPrices builds one price per date with closes rising by 1 from 10
+func dailyPrices(dates ...string) []DailyPrice {
+	prices := make([]DailyPrice, len(dates))
+	for i, d := range dates {
+		c := 10 + float64(i)
+		prices[i] = DailyPrice{Date: d, Open: c - 0.5, High: c + 1, Low: c - 1, Close: c, Volume: 100, Value: 100 * c, Trades: 2}
+	}
+	return prices
+}
+
+func TestAggregateCandles(t *testing.T) {
+	// 2025-04-27 is a Sunday, the first day of an ISX trading week. The week
+	// runs into May.
+	prices := dailyPrices("2025-04-24", "2025-04-27", "2025-04-30", "2025-05-01", "2025-05-03", "2025-05-04")
+
+	tests := []struct {
+		interval string
+		want     []Candle
+	}{
+		{IntervalDay, []Candle{
+			{Time: "2025-04-24", Open: 9.5, High: 11, Low: 9, Close: 10, Volume: 100, Value: 1000, Trades: 2},
+			{Time: "2025-04-27", Open: 10.5, High: 12, Low: 10, Close: 11, Volume: 100, Value: 1100, Trades: 2},
+			{Time: "2025-04-30", Open: 11.5, High: 13, Low: 11, Close: 12, Volume: 100, Value: 1200, Trades: 2},
+			{Time: "2025-05-01", Open: 12.5, High: 14, Low: 12, Close: 13, Volume: 100, Value: 1300, Trades: 2},
+			{Time: "2025-05-03", Open: 13.5, High: 15, Low: 13, Close: 14, Volume: 100, Value: 1400, Trades: 2},
+			{Time: "2025-05-04", Open: 14.5, High: 16, Low: 14, Close: 15, Volume: 100, Value: 1500, Trades: 2},
+		}},
+		{IntervalWeek, []Candle{
+			// Thursday closes the week before
+			{Time: "2025-04-20", Open: 9.5, High: 11, Low: 9, Close: 10, Volume: 100, Value: 1000, Trades: 2},
+			// Sunday to Thursday across the month end, plus a Saturday session
+			{Time: "2025-04-27", Open: 10.5, High: 15, Low: 10, Close: 14, Volume: 400, Value: 5000, Trades: 8},
+			{Time: "2025-05-04", Open: 14.5, High: 16, Low: 14, Close: 15, Volume: 100, Value: 1500, Trades: 2},
+		}},
+		{IntervalMonth, []Candle{
+			{Time: "2025-04-01", Open: 9.5, High: 13, Low: 9, Close: 12, Volume: 300, Value: 3300, Trades: 6},
+			{Time: "2025-05-01", Open: 12.5, High: 16, Low: 12, Close: 15, Volume: 300, Value: 4200, Trades: 6},
+		}},
+	}
+	for _, tt := range tests {
+		if got := AggregateCandles(prices, tt.interval); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s candles:\n got %+v\nwant %+v", tt.interval, got, tt.want)
+		}
+	}
+}
+
+func TestAggregateCandlesHighLow(t *testing.T) {
+	prices := []DailyPrice{
+		{Date: "2025-01-05", Open: 10, High: 12, Low: 9, Close: 11},
+		{Date: "2025-01-06", Open: 11, High: 15, Low: 10, Close: 14},
+		{Date: "2025-01-07", Open: 14, High: 14, Low: 7, Close: 8},
+		{Date: "not a date", Open: 1, High: 100, Low: 0.5, Close: 1},
+	}
+	want := []Candle{{Time: "2025-01-05", Open: 10, High: 15, Low: 7, Close: 8}}
+	if got := AggregateCandles(prices, IntervalWeek); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := AggregateCandles(nil, IntervalMonth); got == nil || len(got) != 0 {
+		t.Errorf("no prices gave %#v, want an empty slice", got)
+	}
+}
+
+func TestPeriodStart(t *testing.T) {
+	tests := []struct {
+		day      string
+		interval string
+		want     string
+	}{
+		{"2025-01-09", IntervalDay, "2025-01-09"},
+		{"2025-01-05", IntervalWeek, "2025-01-05"}, // Sunday
+		{"2025-01-09", IntervalWeek, "2025-01-05"}, // Thursday
+		{"2025-01-11", IntervalWeek, "2025-01-05"}, // Saturday
+		{"2025-01-02", IntervalWeek, "2024-12-29"}, // across the year end
+		{"2025-01-31", IntervalMonth, "2025-01-01"},
+		{"2024-02-29", IntervalMonth, "2024-02-01"},
+	}
+	for _, tt := range tests {
+		day, _ := time.Parse(DateLayout, tt.day)
+		if got := periodStart(day, tt.interval).Format(DateLayout); got != tt.want {
+			t.Errorf("periodStart(%s, %s) = %s, want %s", tt.day, tt.interval, got, tt.want)
+		}
+	}
+
+	// Times of day and time zones do not move the period
+	baghdad := time.FixedZone("AST", 3*60*60)
+	day := time.Date(2025, 1, 5, 1, 30, 0, 0, baghdad)
+	if got := periodStart(day, IntervalWeek).Format(DateLayout); got != "2025-01-05" {
+		t.Errorf("periodStart of Sunday 01:30 in Baghdad = %s", got)
+	}
+}
+
+func TestColumns(t *testing.T) {
+	candles := []Candle{
+		{Time: "2025-01-05", Open: 1, High: 2, Low: 0.5, Close: 1.5, Volume: 10},
+		{Time: "2025-01-12", Open: 1.5, High: 3, Low: 1, Close: 2.5, Volume: 20},
+	}
+	want := CandleColumns{
+		Time:   []string{"2025-01-05", "2025-01-12"},
+		Open:   []float64{1, 1.5},
+		High:   []float64{2, 3},
+		Low:    []float64{0.5, 1},
+		Close:  []float64{1.5, 2.5},
+		Volume: []int64{10, 20},
+	}
+	if got := Columns(candles); !reflect.DeepEqual(got, want) {
+		t.Errorf("Columns = %+v, want %+v", got, want)
+	}
+}
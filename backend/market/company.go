@@ -0,0 +1,257 @@
+package market
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"isxportfolio-backend/registry"
+	"log"
+	"strings"
+	"time"
+)
+
+// Listing states of a company
+const (
+	StatusListed    = "listed"
+	StatusSuspended = "suspended"
+	StatusDelisted  = "delisted"
+)
+
+// Company is a listed company and the details of its ISX profile
+type Company struct {
+	Ticker            string `json:"ticker"`
+	NameAr            string `json:"name_ar"`
+	NameEn            string `json:"name_en"`
+	Sector            string `json:"sector"`
+	ListingDate       string `json:"listing_date,omitempty"` // YYYY-MM-DD
+	PaidUpCapital     int64  `json:"paid_up_capital"`        // IQD
+	SharesOutstanding int64  `json:"shares_outstanding"`
+	Status            string `json:"status"`
+	// ProfileUpdatedAt is when the ISX profile was last scraped, nil if never
+	ProfileUpdatedAt *time.Time `json:"profile_updated_at"`
+}
+
+// CompanyFilter holds the criteria for listing companies
+type CompanyFilter struct {
+	Sector string
+	Status string
+}
+
+// SeedCompanies adds the registry's companies that are not stored yet, so
+// every listed company can be looked up before its profile is scraped
+func (s *Store) SeedCompanies(reg *registry.Registry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	added := 0
+	for _, c := range reg.Companies() {
+		res, err := tx.Exec(`
+			INSERT INTO companies (ticker, name_ar, name_en, sector, status)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(ticker) DO NOTHING`,
+			c.Symbol, c.NameAr, c.NameEn, c.Sector, StatusListed)
+		if err != nil {
+			return fmt.Errorf("error seeding company %s: %w", c.Symbol, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			added++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing companies: %w", err)
+	}
+	if added > 0 {
+		log.Printf("Added %d companies from the registry", added)
+	}
+	return nil
+}
+
+// RegisterCompanies adds the stored companies that the registry does not
+// know, such as ones discovered from news links, so their tickers are
+// treated as listed
+func (s *Store) RegisterCompanies(reg *registry.Registry) error {
+	companies, err := s.Companies(CompanyFilter{})
+	if err != nil {
+		return err
+	}
+	added := 0
+	for _, c := range companies {
+		if reg.Add(registry.Company{Symbol: c.Ticker, NameAr: c.NameAr, NameEn: c.NameEn, Sector: c.Sector}) {
+			added++
+		}
+	}
+	if added > 0 {
+		log.Printf("Added %d stored companies to the registry", added)
+	}
+	return nil
+}
+
+// DiscoverCompanies adds the companies tagged on news, from the ISX
+// profile links of detail pages, that are not stored yet, so their
+// profiles are scraped. It returns the tickers added.
+func (s *Store) DiscoverCompanies() ([]string, error) {
+	rows, err := s.db.Query(`
+		SELECT ticker FROM news_tickers
+		UNION SELECT ticker FROM news_items WHERE ticker <> ''
+		EXCEPT SELECT ticker FROM companies`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying news tickers: %w", err)
+	}
+	var candidates []string
+	for rows.Next() {
+		var ticker string
+		if err := rows.Scan(&ticker); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning news ticker: %w", err)
+		}
+		candidates = append(candidates, ticker)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error querying news tickers: %w", err)
+	}
+
+	var added []string
+	for _, ticker := range candidates {
+		ticker = strings.ToUpper(ticker)
+		if !registry.ValidSymbol(ticker) {
+			continue
+		}
+		res, err := s.db.Exec(`
+			INSERT INTO companies (ticker, status) VALUES (?, ?)
+			ON CONFLICT(ticker) DO NOTHING`, ticker, StatusListed)
+		if err != nil {
+			return nil, fmt.Errorf("error adding company %s: %w", ticker, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			added = append(added, ticker)
+		}
+	}
+	if len(added) > 0 {
+		log.Printf("Discovered %d companies in news: %s", len(added), strings.Join(added, ", "))
+	}
+	return added, nil
+}
+
+// SaveCompanyProfile stores a scraped profile. Fields the profile page did
+// not provide keep their stored values.
+func (s *Store) SaveCompanyProfile(c Company) error {
+	_, err := s.db.Exec(`
+		INSERT INTO companies (ticker, name_ar, name_en, sector, listing_date,
+			paid_up_capital, shares_outstanding, status, profile_updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(ticker) DO UPDATE SET
+			name_ar = CASE WHEN excluded.name_ar <> '' THEN excluded.name_ar ELSE companies.name_ar END,
+			name_en = CASE WHEN excluded.name_en <> '' THEN excluded.name_en ELSE companies.name_en END,
+			sector = CASE WHEN excluded.sector <> '' THEN excluded.sector ELSE companies.sector END,
+			listing_date = CASE WHEN excluded.listing_date <> '' THEN excluded.listing_date ELSE companies.listing_date END,
+			paid_up_capital = CASE WHEN excluded.paid_up_capital > 0 THEN excluded.paid_up_capital ELSE companies.paid_up_capital END,
+			shares_outstanding = CASE WHEN excluded.shares_outstanding > 0 THEN excluded.shares_outstanding ELSE companies.shares_outstanding END,
+			status = CASE WHEN excluded.status <> '' THEN excluded.status ELSE companies.status END,
+			profile_updated_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP`,
+		strings.ToUpper(c.Ticker), c.NameAr, c.NameEn, c.Sector, c.ListingDate,
+		c.PaidUpCapital, c.SharesOutstanding, c.Status)
+	if err != nil {
+		return fmt.Errorf("error saving company %s: %w", c.Ticker, err)
+	}
+	return nil
+}
+
+// Companies lists the stored companies matching the filter by ticker
+func (s *Store) Companies(filter CompanyFilter) ([]Company, error) {
+	var where []string
+	var args []interface{}
+	if filter.Sector != "" {
+		where = append(where, "sector = ?")
+		args = append(args, filter.Sector)
+	}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, filter.Status)
+	}
+	whereSQL := ""
+	if len(where) > 0 {
+		whereSQL = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	rows, err := s.db.Query(companyColumns+" FROM companies "+whereSQL+" ORDER BY ticker", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying companies: %w", err)
+	}
+	defer rows.Close()
+
+	companies := []Company{}
+	for rows.Next() {
+		c, err := scanCompany(rows)
+		if err != nil {
+			return nil, err
+		}
+		companies = append(companies, *c)
+	}
+	return companies, rows.Err()
+}
+
+// Company returns the stored company with the given ticker
+func (s *Store) Company(ticker string) (*Company, error) {
+	row := s.db.QueryRow(companyColumns+" FROM companies WHERE ticker = ?", strings.ToUpper(ticker))
+	c, err := scanCompany(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return c, err
+}
+
+// staleProfiles returns the tickers whose profile was never scraped or was
+// scraped longer than maxAge ago, oldest first
+func (s *Store) staleProfiles(maxAge time.Duration) ([]string, error) {
+	cutoff := time.Now().UTC().Add(-maxAge).Format("2006-01-02 15:04:05")
+	rows, err := s.db.Query(`
+		SELECT ticker FROM companies
+		WHERE status <> ? AND (profile_updated_at IS NULL OR profile_updated_at < ?)
+		ORDER BY profile_updated_at IS NOT NULL, profile_updated_at, ticker`, StatusDelisted, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("error querying company profiles: %w", err)
+	}
+	defer rows.Close()
+
+	var tickers []string
+	for rows.Next() {
+		var ticker string
+		if err := rows.Scan(&ticker); err != nil {
+			return nil, fmt.Errorf("error scanning company ticker: %w", err)
+		}
+		tickers = append(tickers, ticker)
+	}
+	return tickers, rows.Err()
+}
+
+// Columns read by scanCompany
+const companyColumns = `
+	SELECT ticker, name_ar, name_en, sector, listing_date, paid_up_capital,
+		shares_outstanding, status, profile_updated_at`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCompany(row rowScanner) (*Company, error) {
+	var c Company
+	var updated sql.NullTime
+	if err := row.Scan(&c.Ticker, &c.NameAr, &c.NameEn, &c.Sector, &c.ListingDate, &c.PaidUpCapital,
+		&c.SharesOutstanding, &c.Status, &updated); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error scanning company: %w", err)
+	}
+	if updated.Valid {
+		c.ProfileUpdatedAt = &updated.Time
+	}
+	return &c, nil
+}
@@ -0,0 +1,257 @@
+package market
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Constituent weighting methods of computed indices
+const (
+	WeightMarketCap = "market_cap" // close times shares outstanding
+	WeightEqual     = "equal"
+	WeightPrice     = "price" // close only, like a price-weighted average
+)
+
+// Level of a computed index on its first session
+const indexBaseValue = 1000
+
+// Number of companies in the computed ISX60
+const isx60Size = 60
+
+// IndexWeightingFromEnv returns the weighting named by ISX_INDEX_WEIGHTING,
+// falling back to market capitalization
+func IndexWeightingFromEnv() string {
+	w := os.Getenv("ISX_INDEX_WEIGHTING")
+	switch w {
+	case WeightMarketCap, WeightEqual, WeightPrice:
+		return w
+	case "":
+		return WeightMarketCap
+	}
+	log.Printf("Unknown index weighting %q, using %s", w, WeightMarketCap)
+	return WeightMarketCap
+}
+
+// IndexCalculator rebuilds the ISX60 and one index per sector from stored
+// constituent prices
+type IndexCalculator struct {
+	Store     *Store
+	Weighting string
+}
+
+// Constructor for the index calculator
+func NewIndexCalculator(store *Store, weighting string) *IndexCalculator {
+	return &IndexCalculator{Store: store, Weighting: weighting}
+}
+
+// indexMember is a constituent's adjusted closes and traded values by date
+// and its share count
+type indexMember struct {
+	ticker string
+	closes map[string]float64
+	values map[string]float64 // IQD traded, used to pick the ISX60
+	shares int64
+}
+
+// Recompute rebuilds every computed index. Each quarter the ISX60 is made
+// of the 60 companies with the highest traded value in the quarter before,
+// so companies that later stopped trading still count while they were
+// among the largest; sector indices hold every company of the sector.
+// Prices are adjusted for corporate actions so bonus issues do not show as
+// falls. A market capitalization index leaves out companies whose share
+// count is not known.
+func (c *IndexCalculator) Recompute() error {
+	companies, err := c.Store.Companies(CompanyFilter{})
+	if err != nil {
+		return err
+	}
+
+	sectors := make(map[string][]indexMember)
+	var all []indexMember
+	var noShares []string
+	for _, company := range companies {
+		prices, err := c.Store.Prices(company.Ticker, time.Time{}, time.Time{})
+		if err != nil {
+			return err
+		}
+		if len(prices) == 0 {
+			continue
+		}
+		if prices, err = c.Store.Adjust(company.Ticker, prices); err != nil {
+			return err
+		}
+
+		if c.Weighting == WeightMarketCap && company.SharesOutstanding <= 0 {
+			noShares = append(noShares, company.Ticker)
+			continue
+		}
+
+		m := indexMember{
+			ticker: company.Ticker,
+			closes: make(map[string]float64),
+			values: make(map[string]float64),
+			shares: company.SharesOutstanding,
+		}
+		for _, p := range prices {
+			m.closes[p.Date] = p.Close
+			m.values[p.Date] = p.Value
+		}
+		all = append(all, m)
+		if company.Sector != "" {
+			sectors[company.Sector] = append(sectors[company.Sector], m)
+		}
+	}
+
+	if len(noShares) > 0 {
+		log.Printf("Leaving %d companies without a share count out of the computed indices: %s",
+			len(noShares), strings.Join(noShares, ", "))
+	}
+
+	if err := c.save(IndexISX60, all, isx60Constituents(all)); err != nil {
+		return err
+	}
+	for sector, members := range sectors {
+		if err := c.save(strings.ToUpper(sector), members, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// save computes one index and replaces its stored levels
+func (c *IndexCalculator) save(code string, members []indexMember, include func(ticker, date string) bool) error {
+	points := computeIndex(members, c.Weighting, include)
+	if err := c.Store.SaveIndexHistory(code, IndexComputed, points, true); err != nil {
+		return fmt.Errorf("error saving computed %s: %w", code, err)
+	}
+	return nil
+}
+
+// isx60Constituents picks the ISX60 of each quarter: the isx60Size
+// members with the highest traded value in the previous quarter, or in the
+// first quarter itself since nothing comes before it. The result reports
+// whether a ticker is a constituent on a date.
+func isx60Constituents(members []indexMember) func(ticker, date string) bool {
+	// Traded value of each member by quarter
+	totals := make(map[string]map[string]float64)
+	for _, m := range members {
+		for d, v := range m.values {
+			q := quarterOf(d)
+			if totals[q] == nil {
+				totals[q] = make(map[string]float64)
+			}
+			totals[q][m.ticker] += v
+		}
+	}
+	quarters := make([]string, 0, len(totals))
+	for q := range totals {
+		quarters = append(quarters, q)
+	}
+	sort.Strings(quarters)
+
+	constituents := make(map[string]map[string]bool, len(quarters))
+	for i, q := range quarters {
+		basis := totals[q]
+		if i > 0 {
+			basis = totals[quarters[i-1]]
+		}
+		tickers := make([]string, 0, len(basis))
+		for t, v := range basis {
+			if v > 0 {
+				tickers = append(tickers, t)
+			}
+		}
+		sort.Slice(tickers, func(a, b int) bool {
+			if basis[tickers[a]] != basis[tickers[b]] {
+				return basis[tickers[a]] > basis[tickers[b]]
+			}
+			return tickers[a] < tickers[b]
+		})
+		constituents[q] = make(map[string]bool)
+		for _, t := range tickers[:min(len(tickers), isx60Size)] {
+			constituents[q][t] = true
+		}
+	}
+
+	return func(ticker, date string) bool {
+		return constituents[quarterOf(date)][ticker]
+	}
+}
+
+// quarterOf returns the calendar quarter of a YYYY-MM-DD date, e.g. 2024Q3
+func quarterOf(date string) string {
+	if len(date) < 7 {
+		return ""
+	}
+	month := int(date[5]-'0')*10 + int(date[6]-'0')
+	return fmt.Sprintf("%sQ%d", date[:4], (month-1)/3+1)
+}
+
+// computeIndex chains daily returns into index levels starting at
+// indexBaseValue. Each session's return is the weighted average of the
+// returns of that session's constituents, as told by include, from their
+// previous close; every member counts when include is nil. A constituent
+// that did not trade keeps its last close, and one joins once it has a
+// close to compare with.
+func computeIndex(members []indexMember, weighting string, include func(ticker, date string) bool) []IndexPoint {
+	dateSet := make(map[string]bool)
+	for _, m := range members {
+		for d := range m.closes {
+			dateSet[d] = true
+		}
+	}
+	dates := make([]string, 0, len(dateSet))
+	for d := range dateSet {
+		dates = append(dates, d)
+	}
+	sort.Strings(dates)
+
+	last := make(map[string]float64)
+	level := float64(indexBaseValue)
+	points := make([]IndexPoint, 0, len(dates))
+	for _, d := range dates {
+		var weighted, total float64
+		for _, m := range members {
+			prev, ok := last[m.ticker]
+			if !ok || (include != nil && !include(m.ticker, d)) {
+				continue
+			}
+			cur, traded := m.closes[d]
+			if !traded {
+				cur = prev
+			}
+			w := memberWeight(m, prev, weighting)
+			weighted += w * cur / prev
+			total += w
+		}
+		if total > 0 {
+			level *= weighted / total
+		}
+		for _, m := range members {
+			if close, ok := m.closes[d]; ok {
+				last[m.ticker] = close
+			}
+		}
+		points = append(points, IndexPoint{Date: d, Value: math.Round(level*100) / 100})
+	}
+	return points
+}
+
+// memberWeight returns a constituent's weight given its previous close.
+// Companies without a known share count have no weight in a market
+// capitalization index.
+func memberWeight(m indexMember, prevClose float64, weighting string) float64 {
+	switch weighting {
+	case WeightEqual:
+		return 1
+	case WeightPrice:
+		return prevClose
+	default:
+		return prevClose * float64(m.shares)
+	}
+}
@@ -0,0 +1,158 @@
+package market
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestQuarterOf(t *testing.T) {
+	tests := []struct {
+		date string
+		want string
+	}{
+		{"2024-01-01", "2024Q1"},
+		{"2024-03-31", "2024Q1"},
+		{"2024-04-01", "2024Q2"},
+		{"2024-09-30", "2024Q3"},
+		{"2024-12-31", "2024Q4"},
+		{"2024", ""},
+	}
+	for _, tt := range tests {
+		if got := quarterOf(tt.date); got != tt.want {
+			t.Errorf("quarterOf(%q) = %q, want %q", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestComputeIndex(t *testing.T) {
+	// BBB does not trade on the 3rd session and keeps its last close
+	members := []indexMember{
+		{ticker: "AAA", shares: 100, closes: map[string]float64{"2024-01-01": 10, "2024-01-02": 11, "2024-01-03": 11}},
+		{ticker: "BBB", shares: 300, closes: map[string]float64{"2024-01-01": 20, "2024-01-02": 18}},
+	}
+
+	tests := []struct {
+		weighting string
+		want      []float64
+	}{
+		// Returns of 1.1 and 0.9 weighted by capitalization 1000 and 6000
+		{WeightMarketCap, []float64{1000, 928.57, 928.57}},
+		{WeightEqual, []float64{1000, 1000, 1000}},
+		// Weighted by previous closes 10 and 20
+		{WeightPrice, []float64{1000, 966.67, 966.67}},
+	}
+	for _, tt := range tests {
+		points := computeIndex(members, tt.weighting, nil)
+		want := []IndexPoint{
+			{Date: "2024-01-01", Value: tt.want[0]},
+			{Date: "2024-01-02", Value: tt.want[1]},
+			{Date: "2024-01-03", Value: tt.want[2]},
+		}
+		if !reflect.DeepEqual(points, want) {
+			t.Errorf("%s weighting: got %+v, want %+v", tt.weighting, points, want)
+		}
+	}
+}
+
+func TestComputeIndexJoinAndInclude(t *testing.T) {
+	members := []indexMember{
+		{ticker: "AAA", closes: map[string]float64{"2024-01-01": 10, "2024-01-02": 10, "2024-01-03": 10}},
+		{ticker: "CCC", closes: map[string]float64{"2024-01-02": 5, "2024-01-03": 6}},
+	}
+
+	// CCC counts from the session after its first close
+	points := computeIndex(members, WeightEqual, nil)
+	want := []IndexPoint{{Date: "2024-01-01", Value: 1000}, {Date: "2024-01-02", Value: 1000}, {Date: "2024-01-03", Value: 1100}}
+	if !reflect.DeepEqual(points, want) {
+		t.Errorf("got %+v, want %+v", points, want)
+	}
+
+	// A member left out on a session does not move the index that day
+	exclude := func(ticker, date string) bool { return ticker != "CCC" || date != "2024-01-03" }
+	points = computeIndex(members, WeightEqual, exclude)
+	want = []IndexPoint{{Date: "2024-01-01", Value: 1000}, {Date: "2024-01-02", Value: 1000}, {Date: "2024-01-03", Value: 1000}}
+	if !reflect.DeepEqual(points, want) {
+		t.Errorf("with CCC left out: got %+v, want %+v", points, want)
+	}
+
+	if points := computeIndex(nil, WeightEqual, nil); len(points) != 0 {
+		t.Errorf("no members gave %+v", points)
+	}
+}
+
+func TestISX60Constituents(t *testing.T) {
+	// 61 companies. In 2024Q1 T01 trades the most and T61 the least. In
+	// 2024Q2 T61 trades the most and T60 stops trading. Some company trades
+	// in 2024Q3 so the quarter exists.
+	var members []indexMember
+	for i := 1; i <= 61; i++ {
+		m := indexMember{
+			ticker: fmt.Sprintf("T%02d", i),
+			values: map[string]float64{"2024-01-15": float64(1000 - i)},
+		}
+		switch {
+		case i == 61:
+			m.values["2024-04-15"] = 5000
+		case i != 60:
+			m.values["2024-04-15"] = 100
+		}
+		if i == 1 {
+			m.values["2024-07-15"] = 100
+		}
+		members = append(members, m)
+	}
+
+	include := isx60Constituents(members)
+	tests := []struct {
+		ticker, date string
+		want         bool
+	}{
+		// The first quarter is picked on its own values
+		{"T01", "2024-02-01", true},
+		{"T60", "2024-02-01", true},
+		{"T61", "2024-02-01", false},
+		// 2024Q2 is picked on 2024Q1, so T60 stays in without trading
+		// and T61 is not in yet
+		{"T60", "2024-05-01", true},
+		{"T61", "2024-05-01", false},
+		// 2024Q3 is picked on 2024Q2
+		{"T61", "2024-08-01", true},
+		{"T60", "2024-08-01", false},
+		{"T59", "2024-08-01", true},
+		// No trading at all in 2024Q4
+		{"T01", "2024-11-01", false},
+	}
+	for _, tt := range tests {
+		if got := include(tt.ticker, tt.date); got != tt.want {
+			t.Errorf("%s on %s: constituent = %v, want %v", tt.ticker, tt.date, got, tt.want)
+		}
+	}
+
+	count := 0
+	for _, m := range members {
+		if include(m.ticker, "2024-08-01") {
+			count++
+		}
+	}
+	if count != isx60Size {
+		t.Errorf("2024Q3 has %d constituents, want %d", count, isx60Size)
+	}
+}
+
+func TestISX60ConstituentsTies(t *testing.T) {
+	// Equal values are broken by ticker so the pick does not depend on map order
+	var members []indexMember
+	for i := 62; i >= 1; i-- {
+		members = append(members, indexMember{
+			ticker: fmt.Sprintf("T%02d", i),
+			values: map[string]float64{"2024-01-15": 10},
+		})
+	}
+	include := isx60Constituents(members)
+	for ticker, want := range map[string]bool{"T01": true, "T60": true, "T61": false, "T62": false} {
+		if got := include(ticker, "2024-01-15"); got != want {
+			t.Errorf("%s: constituent = %v, want %v", ticker, got, want)
+		}
+	}
+}
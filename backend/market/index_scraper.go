@@ -0,0 +1,67 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"isxportfolio-backend/calendar"
+	"isxportfolio-backend/scraper"
+	"time"
+)
+
+// ISX portal page with an index's daily levels over a date range
+const indexHistoryURL = "http://www.isx-iq.net/isxportal/portal/indexHistory.html?indexCode=%s&fromDate=%s&toDate=%s&currLanguage=en"
+
+// IndexScraper loads the official ISX60 levels from the ISX website
+type IndexScraper struct {
+	Store    *Store
+	Fetcher  scraper.PageFetcher
+	Calendar *calendar.Calendar
+}
+
+// Constructor for the index scraper
+func NewIndexScraper(store *Store, fetcher scraper.PageFetcher, cal *calendar.Calendar) *IndexScraper {
+	return &IndexScraper{Store: store, Fetcher: fetcher, Calendar: cal}
+}
+
+// Update stores the official ISX60 levels since the last stored one,
+// going back at most maxCatchUpDays
+func (i *IndexScraper) Update(ctx context.Context) error {
+	now := time.Now().In(i.Calendar.Location())
+	latest, err := i.Store.LatestIndexDate(IndexISX60, IndexOfficial)
+	if err != nil {
+		return err
+	}
+	from := now.AddDate(0, 0, -maxCatchUpDays)
+	if latest.After(from) {
+		from = latest.AddDate(0, 0, 1)
+	}
+	if from.After(now) {
+		return nil
+	}
+	return i.Backfill(ctx, from, now)
+}
+
+// Backfill stores the official ISX60 levels between from and to
+func (i *IndexScraper) Backfill(ctx context.Context, from, to time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, pricePageTimeout)
+	defer cancel()
+
+	url := fmt.Sprintf(indexHistoryURL, IndexISX60, from.Format(portalDateLayout), to.Format(portalDateLayout))
+	html, err := i.Fetcher.Fetch(ctx, url, "")
+	if err != nil {
+		return fmt.Errorf("failed to get %s history: %w", IndexISX60, err)
+	}
+	points, err := ParseIndexHistory(html)
+	if err != nil {
+		return err
+	}
+
+	first, last := from.Format(DateLayout), to.Format(DateLayout)
+	kept := points[:0]
+	for _, p := range points {
+		if p.Date >= first && p.Date <= last {
+			kept = append(kept, p)
+		}
+	}
+	return i.Store.SaveIndexHistory(IndexISX60, IndexOfficial, kept, false)
+}
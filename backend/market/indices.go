@@ -0,0 +1,166 @@
+package market
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"isxportfolio-backend/arabic"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Code of the ISX60 index, the exchange's main benchmark
+const IndexISX60 = "ISX60"
+
+// Sources of index levels
+const (
+	IndexOfficial = "official" // published by ISX
+	IndexComputed = "computed" // rebuilt from constituent prices
+)
+
+// IndexPoint is an index level at the close of a session
+type IndexPoint struct {
+	Date  string  `json:"date"` // YYYY-MM-DD
+	Value float64 `json:"value"`
+}
+
+// Column headers of the ISX index history table in Arabic and English
+var indexColumns = map[string][]string{
+	"date":  {"التاريخ", "تاريخ الجلسه", "date", "session date"},
+	"value": {"قيمه المؤشر", "المؤشر", "اغلاق المؤشر", "index value", "index close", "closing value", "close", "isx60"},
+}
+
+// ParseIndexHistory reads a table of daily index levels. Rows without a
+// date or a positive level are skipped.
+func ParseIndexHistory(html string) ([]IndexPoint, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse index history: %w", err)
+	}
+
+	var points []IndexPoint
+	doc.Find("table").Each(func(i int, table *goquery.Selection) {
+		columns := map[string]int{}
+		table.Find("tr").Each(func(j int, row *goquery.Selection) {
+			cells := row.Find("th, td")
+			if len(columns) == 0 {
+				columns = tableHeader(cells, indexColumns, "value")
+				return
+			}
+
+			text := func(field string) string {
+				col, ok := columns[field]
+				if !ok || col >= cells.Length() {
+					return ""
+				}
+				return arabic.Clean(cells.Eq(col).Text())
+			}
+
+			p := IndexPoint{Value: parsePrice(text("value"))}
+			for _, layout := range priceDateLayouts {
+				if t, err := arabic.ParseDate(layout, text("date")); err == nil {
+					p.Date = t.Format(DateLayout)
+					break
+				}
+			}
+			if p.Date == "" || p.Value <= 0 {
+				return
+			}
+			points = append(points, p)
+		})
+	})
+
+	return points, nil
+}
+
+// SaveIndexHistory stores index levels from one source. With replace, the
+// code's existing levels from that source are deleted first, which is how
+// computed indices are rebuilt.
+func (s *Store) SaveIndexHistory(code, source string, points []IndexPoint, replace bool) error {
+	code = strings.ToUpper(code)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if replace {
+		if _, err := tx.Exec("DELETE FROM index_history WHERE code = ? AND source = ?", code, source); err != nil {
+			return fmt.Errorf("error clearing %s history of %s: %w", source, code, err)
+		}
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO index_history (code, source, date, value)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(code, source, date) DO UPDATE SET
+			value = excluded.value,
+			updated_at = CURRENT_TIMESTAMP`)
+	if err != nil {
+		return fmt.Errorf("error preparing index statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, p := range points {
+		if _, err := stmt.Exec(code, source, p.Date, p.Value); err != nil {
+			return fmt.Errorf("error saving %s level on %s: %w", code, p.Date, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing index history: %w", err)
+	}
+	log.Printf("Saved %d %s levels of %s", len(points), source, code)
+	return nil
+}
+
+// IndexHistory returns an index's levels from one source between from and
+// to, inclusive, oldest first. Zero times leave the range open.
+func (s *Store) IndexHistory(code, source string, from, to time.Time) ([]IndexPoint, error) {
+	where := []string{"code = ?", "source = ?"}
+	args := []interface{}{strings.ToUpper(code), source}
+	if !from.IsZero() {
+		where = append(where, "date >= ?")
+		args = append(args, from.Format(DateLayout))
+	}
+	if !to.IsZero() {
+		where = append(where, "date <= ?")
+		args = append(args, to.Format(DateLayout))
+	}
+
+	rows, err := s.db.Query(`
+		SELECT date, value FROM index_history
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY date`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying index history: %w", err)
+	}
+	defer rows.Close()
+
+	points := []IndexPoint{}
+	for rows.Next() {
+		var p IndexPoint
+		if err := rows.Scan(&p.Date, &p.Value); err != nil {
+			return nil, fmt.Errorf("error scanning index level: %w", err)
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// LatestIndexDate returns the most recent date of an index from one source,
+// or the zero time if there is none
+func (s *Store) LatestIndexDate(code, source string) (time.Time, error) {
+	var date sql.NullString
+	if err := s.db.QueryRow("SELECT MAX(date) FROM index_history WHERE code = ? AND source = ?",
+		strings.ToUpper(code), source).Scan(&date); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, fmt.Errorf("error querying latest index date: %w", err)
+	}
+	if !date.Valid {
+		return time.Time{}, nil
+	}
+	return time.Parse(DateLayout, date.String)
+}
@@ -0,0 +1,228 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"isxportfolio-backend/calendar"
+	"isxportfolio-backend/scraper"
+	"log"
+	"sync"
+	"time"
+)
+
+// ISX portal pages with daily trading results: every company's results for
+// one session, and one company's results over a date range
+const (
+	sessionResultsURL = "http://www.isx-iq.net/isxportal/portal/sessionResults.html?sessionDate=%s&currLanguage=en"
+	priceHistoryURL   = "http://www.isx-iq.net/isxportal/portal/companyPriceHistory.html?companyCode=%s&fromDate=%s&toDate=%s&currLanguage=en"
+)
+
+// Layout of dates in ISX portal URLs
+const portalDateLayout = "02/01/2006"
+
+// Sessions missed while the server was down are collected on the next run,
+// up to this many days back
+const maxCatchUpDays = 30
+
+// Maximum time to spend loading a single results page
+const pricePageTimeout = 2 * time.Minute
+
+// ErrBackfillRunning is returned when a price backfill is already running
+var ErrBackfillRunning = errors.New("price backfill already running")
+
+// ErrEmptySession is returned when the results page of a trading day has
+// no prices, usually because ISX has not published them yet
+var ErrEmptySession = errors.New("session results page has no prices")
+
+// PriceScraper loads daily trading results from the ISX website
+type PriceScraper struct {
+	Store    *Store
+	Fetcher  scraper.PageFetcher
+	Calendar *calendar.Calendar
+
+	backfill sync.Mutex
+}
+
+// Constructor for the price scraper
+func NewPriceScraper(store *Store, fetcher scraper.PageFetcher, cal *calendar.Calendar) *PriceScraper {
+	return &PriceScraper{Store: store, Fetcher: fetcher, Calendar: cal}
+}
+
+// ScrapeSession loads and stores every company's results for one session.
+// A page without prices is an error, so the session is tried again later.
+func (p *PriceScraper) ScrapeSession(ctx context.Context, day time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, pricePageTimeout)
+	defer cancel()
+
+	html, err := p.Fetcher.Fetch(ctx, fmt.Sprintf(sessionResultsURL, day.Format(portalDateLayout)), "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get session results for %s: %w", day.Format(DateLayout), err)
+	}
+	prices, err := ParseTradingResults(html, DailyPrice{Date: day.Format(DateLayout)})
+	if err != nil {
+		return 0, err
+	}
+	if len(prices) == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrEmptySession, day.Format(DateLayout))
+	}
+	if err := p.Store.SavePrices(prices); err != nil {
+		return 0, err
+	}
+	return len(prices), nil
+}
+
+// Update stores the results of every closed session of the last
+// maxCatchUpDays that has no stored prices, so a run after downtime or on
+// an empty database fills the gap. It fails if a session could not be
+// loaded or had no prices; the next run tries it again, until it is older
+// than maxCatchUpDays or marked as a holiday in the calendar.
+func (p *PriceScraper) Update(ctx context.Context) error {
+	now := time.Now().In(p.Calendar.Location())
+	from := now.AddDate(0, 0, -maxCatchUpDays)
+
+	done, err := p.Store.SessionDates(from, now)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	stored := 0
+	for _, day := range p.closedSessions(from, now) {
+		if done[day.Format(DateLayout)] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := p.ScrapeSession(ctx, day)
+		if err != nil {
+			log.Printf("Error scraping trading session: %v", err)
+			failed = append(failed, day.Format(DateLayout))
+			continue
+		}
+		log.Printf("Stored %d prices for the %s session", n, day.Format(DateLayout))
+		stored++
+	}
+
+	// Factors depend on the closes around each ex-date
+	if stored > 0 {
+		if err := p.Store.RecomputeAllAdjustments(); err != nil {
+			log.Printf("Error recomputing price adjustments: %v", err)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to load sessions %v", failed)
+	}
+	return nil
+}
+
+// closedSessions lists the trading days from from to now whose session has
+// ended by now
+func (p *PriceScraper) closedSessions(from, now time.Time) []time.Time {
+	var days []time.Time
+	loc := p.Calendar.Location()
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	for !day.After(now) {
+		if _, closeAt, ok := p.Calendar.Session(day); ok && !closeAt.After(now) {
+			days = append(days, day)
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return days
+}
+
+// Backfill loads each ticker's price history between from and to, one
+// company at a time. Tickers default to every listed company. Only one
+// backfill runs at a time.
+func (p *PriceScraper) Backfill(ctx context.Context, tickers []string, from, to time.Time) error {
+	if !p.backfill.TryLock() {
+		return ErrBackfillRunning
+	}
+	defer p.backfill.Unlock()
+	return p.runBackfill(ctx, tickers, from, to)
+}
+
+// StartBackfill starts a backfill in the background, or returns
+// ErrBackfillRunning if one is already running
+func (p *PriceScraper) StartBackfill(tickers []string, from, to time.Time) error {
+	if !p.backfill.TryLock() {
+		return ErrBackfillRunning
+	}
+	go func() {
+		defer p.backfill.Unlock()
+		if err := p.runBackfill(context.Background(), tickers, from, to); err != nil {
+			log.Printf("Price backfill failed: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (p *PriceScraper) runBackfill(ctx context.Context, tickers []string, from, to time.Time) error {
+	if len(tickers) == 0 {
+		companies, err := p.Store.Companies(CompanyFilter{})
+		if err != nil {
+			return err
+		}
+		for _, c := range companies {
+			if c.Status != StatusDelisted {
+				tickers = append(tickers, c.Ticker)
+			}
+		}
+	}
+
+	log.Printf("=== Starting price backfill of %d tickers from %s to %s ===",
+		len(tickers), from.Format(DateLayout), to.Format(DateLayout))
+	var failed int
+	for i, ticker := range tickers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := p.backfillTicker(ctx, ticker, from, to)
+		if err != nil {
+			log.Printf("Error backfilling prices of %s: %v", ticker, err)
+			failed++
+			continue
+		}
+		log.Printf("Backfilled %d prices of %s (%d of %d)", n, ticker, i+1, len(tickers))
+	}
+	log.Println("=== Price backfill finished ===")
+	if err := p.Store.RecomputeAllAdjustments(); err != nil {
+		log.Printf("Error recomputing price adjustments: %v", err)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to backfill %d of %d tickers", failed, len(tickers))
+	}
+	return nil
+}
+
+// backfillTicker loads and stores one company's price history
+func (p *PriceScraper) backfillTicker(ctx context.Context, ticker string, from, to time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, pricePageTimeout)
+	defer cancel()
+
+	url := fmt.Sprintf(priceHistoryURL, ticker, from.Format(portalDateLayout), to.Format(portalDateLayout))
+	html, err := p.Fetcher.Fetch(ctx, url, "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get price history: %w", err)
+	}
+	prices, err := ParseTradingResults(html, DailyPrice{Ticker: ticker})
+	if err != nil {
+		return 0, err
+	}
+
+	// The history page may pad the range, keep only what was asked for
+	first, last := from.Format(DateLayout), to.Format(DateLayout)
+	kept := prices[:0]
+	for _, price := range prices {
+		if price.Ticker == ticker && price.Date >= first && price.Date <= last {
+			kept = append(kept, price)
+		}
+	}
+	if err := p.Store.SavePrices(kept); err != nil {
+		return 0, err
+	}
+	return len(kept), nil
+}
@@ -0,0 +1,247 @@
+package market
+
+import (
+	"fmt"
+	"isxportfolio-backend/arabic"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Layout of trading session dates
+const DateLayout = "2006-01-02"
+
+// DailyPrice is one ticker's trading results for one session
+type DailyPrice struct {
+	Ticker string  `json:"ticker"`
+	Date   string  `json:"date"` // YYYY-MM-DD
+	Open   float64 `json:"open"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Close  float64 `json:"close"`
+	Volume int64   `json:"volume"` // shares traded
+	Value  float64 `json:"value"`  // IQD traded
+	Trades int64   `json:"trades"`
+}
+
+// Column headers of the ISX trading result tables in Arabic and English
+var priceColumns = map[string][]string{
+	"ticker": {"رمز الشركه", "الرمز", "code", "symbol", "company code"},
+	"date":   {"التاريخ", "تاريخ الجلسه", "date", "session date"},
+	"open":   {"سعر الافتتاح", "الافتتاح", "opening price", "open"},
+	"high":   {"اعلى سعر", "أعلى سعر", "highest price", "high"},
+	"low":    {"ادنى سعر", "أدنى سعر", "lowest price", "low"},
+	"close":  {"سعر الاغلاق", "سعر الإغلاق", "الاغلاق", "closing price", "close"},
+	"volume": {"عدد الاسهم المتداوله", "الاسهم المتداوله", "traded shares", "no. of shares", "volume"},
+	"value":  {"القيمه المتداوله", "قيمه التداول", "traded value", "value"},
+	"trades": {"عدد الصفقات", "الصفقات", "no. of trades", "number of trades", "trades"},
+}
+
+// Date layouts used in the trading result tables
+var priceDateLayouts = []string{"02/01/2006", "2/1/2006", "2006-01-02", "02-01-2006"}
+
+// ParseTradingResults reads a table of daily trading results. Rows take
+// their ticker and date from the table when it has those columns and from
+// defaults otherwise, so the same parser reads the all-company session
+// results and a single company's price history. Rows without a ticker,
+// date or closing price are skipped.
+func ParseTradingResults(html string, defaults DailyPrice) ([]DailyPrice, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trading results: %w", err)
+	}
+
+	var prices []DailyPrice
+	doc.Find("table").Each(func(i int, table *goquery.Selection) {
+		columns := map[string]int{}
+		table.Find("tr").Each(func(j int, row *goquery.Selection) {
+			cells := row.Find("th, td")
+			if len(columns) == 0 {
+				columns = tableHeader(cells, priceColumns, "close")
+				return
+			}
+
+			text := func(field string) string {
+				col, ok := columns[field]
+				if !ok || col >= cells.Length() {
+					return ""
+				}
+				return arabic.Clean(cells.Eq(col).Text())
+			}
+
+			p := defaults
+			if t := strings.ToUpper(text("ticker")); t != "" {
+				p.Ticker = t
+			}
+			if d := text("date"); d != "" {
+				p.Date = ""
+				for _, layout := range priceDateLayouts {
+					if t, err := arabic.ParseDate(layout, d); err == nil {
+						p.Date = t.Format(DateLayout)
+						break
+					}
+				}
+			}
+			p.Open = parsePrice(text("open"))
+			p.High = parsePrice(text("high"))
+			p.Low = parsePrice(text("low"))
+			p.Close = parsePrice(text("close"))
+			p.Value = parsePrice(text("value"))
+			p.Volume, _ = parseAmount(text("volume"))
+			p.Trades, _ = parseAmount(text("trades"))
+			if p.Ticker == "" || p.Date == "" || p.Close <= 0 {
+				return
+			}
+
+			// Some sessions only report a close for thinly traded tickers
+			if p.Open <= 0 {
+				p.Open = p.Close
+			}
+			if p.High <= 0 {
+				p.High = max(p.Open, p.Close)
+			}
+			if p.Low <= 0 {
+				p.Low = min(p.Open, p.Close)
+			}
+			prices = append(prices, p)
+		})
+	})
+
+	return prices, nil
+}
+
+// tableHeader maps fields to column indexes using the header labels in
+// labels. It returns an empty map unless the row has the required column.
+func tableHeader(cells *goquery.Selection, labels map[string][]string, required string) map[string]int {
+	columns := map[string]int{}
+	cells.Each(func(i int, cell *goquery.Selection) {
+		label := arabic.Normalize(cell.Text())
+		for field, names := range labels {
+			if _, done := columns[field]; done {
+				continue
+			}
+			for _, name := range names {
+				if label == arabic.Normalize(name) {
+					columns[field] = i
+					break
+				}
+			}
+		}
+	})
+	if _, ok := columns[required]; !ok {
+		return map[string]int{}
+	}
+	return columns
+}
+
+// parsePrice reads a price or value, returning 0 if there is none
+func parsePrice(s string) float64 {
+	f, err := arabic.ParseFloat(s)
+	if err != nil || f < 0 {
+		return 0
+	}
+	return f
+}
+
+// SavePrices inserts or replaces daily prices. Saving the same session
+// twice leaves a single row per ticker and date.
+func (s *Store) SavePrices(prices []DailyPrice) error {
+	if len(prices) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO price_history (ticker, date, open, high, low, close, volume, value, trades)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(ticker, date) DO UPDATE SET
+			open = excluded.open,
+			high = excluded.high,
+			low = excluded.low,
+			close = excluded.close,
+			volume = excluded.volume,
+			value = excluded.value,
+			trades = excluded.trades,
+			updated_at = CURRENT_TIMESTAMP`)
+	if err != nil {
+		return fmt.Errorf("error preparing price statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, p := range prices {
+		if _, err := stmt.Exec(strings.ToUpper(p.Ticker), p.Date, p.Open, p.High, p.Low, p.Close,
+			p.Volume, p.Value, p.Trades); err != nil {
+			return fmt.Errorf("error saving price of %s on %s: %w", p.Ticker, p.Date, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing prices: %w", err)
+	}
+	log.Printf("Saved %d daily prices", len(prices))
+	return nil
+}
+
+// Prices returns a ticker's daily prices between from and to, inclusive,
+// oldest first. Zero times leave the range open.
+func (s *Store) Prices(ticker string, from, to time.Time) ([]DailyPrice, error) {
+	where := []string{"ticker = ?"}
+	args := []interface{}{strings.ToUpper(ticker)}
+	if !from.IsZero() {
+		where = append(where, "date >= ?")
+		args = append(args, from.Format(DateLayout))
+	}
+	if !to.IsZero() {
+		where = append(where, "date <= ?")
+		args = append(args, to.Format(DateLayout))
+	}
+
+	rows, err := s.db.Query(`
+		SELECT ticker, date, open, high, low, close, volume, value, trades
+		FROM price_history
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY date`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying prices: %w", err)
+	}
+	defer rows.Close()
+
+	prices := []DailyPrice{}
+	for rows.Next() {
+		var p DailyPrice
+		if err := rows.Scan(&p.Ticker, &p.Date, &p.Open, &p.High, &p.Low, &p.Close,
+			&p.Volume, &p.Value, &p.Trades); err != nil {
+			return nil, fmt.Errorf("error scanning price: %w", err)
+		}
+		prices = append(prices, p)
+	}
+	return prices, rows.Err()
+}
+
+// SessionDates returns the dates between from and to, inclusive, that have
+// stored prices
+func (s *Store) SessionDates(from, to time.Time) (map[string]bool, error) {
+	rows, err := s.db.Query("SELECT DISTINCT date FROM price_history WHERE date >= ? AND date <= ?",
+		from.Format(DateLayout), to.Format(DateLayout))
+	if err != nil {
+		return nil, fmt.Errorf("error querying stored sessions: %w", err)
+	}
+	defer rows.Close()
+
+	dates := make(map[string]bool)
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return nil, fmt.Errorf("error scanning session date: %w", err)
+		}
+		dates[date] = true
+	}
+	return dates, rows.Err()
+}
@@ -0,0 +1,281 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"isxportfolio-backend/arabic"
+	"isxportfolio-backend/registry"
+	"isxportfolio-backend/scraper"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Company profile page on the ISX portal, by ticker and language
+const profileURL = "http://www.isx-iq.net/isxportal/portal/companyprofilecontainer.html?companyCode=%s&currLanguage=%s"
+
+// Profiles scraped more recently than this are not fetched again
+const profileMaxAge = 6 * 24 * time.Hour
+
+// Maximum time to spend loading a single profile page
+const profilePageTimeout = time.Minute
+
+// ErrEmptyProfile is returned when neither profile page gave a company's
+// name, sector or share count, as with an error page served as HTTP 200
+var ErrEmptyProfile = errors.New("profile pages have no company details")
+
+// Labels of the profile fields in Arabic and English
+var profileLabels = map[string][]string{
+	"name":    {"اسم الشركه", "company name"},
+	"sector":  {"القطاع", "sector"},
+	"listing": {"تاريخ الادراج", "تاريخ الإدراج", "listing date", "date of listing"},
+	"capital": {"راس المال المدفوع", "رأس المال المدفوع", "راس المال", "paid up capital", "paid-up capital", "capital"},
+	"shares":  {"عدد الاسهم", "الاسهم المصدره", "number of shares", "shares outstanding", "issued shares"},
+	"status":  {"حاله الشركه", "الحاله", "company status", "status"},
+}
+
+// Profile sector names mapped to the registry's sector IDs
+var sectorNames = []struct {
+	keyword string
+	sector  string
+}{
+	{"مصارف", "banks"}, {"مصرف", "banks"}, {"bank", "banks"},
+	{"تامين", "insurance"}, {"insurance", "insurance"},
+	{"اتصالات", "telecom"}, {"telecom", "telecom"},
+	{"تحويل مالي", "money_transfer"}, {"money transfer", "money_transfer"},
+	{"استثمار", "investment"}, {"investment", "investment"},
+	{"فنادق", "hotels"}, {"سياح", "hotels"}, {"hotel", "hotels"}, {"touris", "hotels"},
+	{"زراع", "agriculture"}, {"agricultur", "agriculture"},
+	{"صناع", "industry"}, {"industr", "industry"},
+	{"خدم", "services"}, {"servic", "services"},
+}
+
+// Date layouts seen on profile pages
+var profileDateLayouts = []string{"2006-01-02", "2/1/2006", "02/01/2006", "2-1-2006", "02-01-2006"}
+
+// ParseCompanyProfile extracts the company details from a profile page in
+// lang. The page lists each field as a label and value in a table row or
+// a definition list; fields that cannot be read are left empty.
+func ParseCompanyProfile(html, lang string) (Company, error) {
+	var c Company
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return c, fmt.Errorf("failed to parse company profile: %w", err)
+	}
+
+	fields := make(map[string]string)
+	record := func(label, value string) {
+		label = strings.TrimSpace(strings.TrimSuffix(arabic.Normalize(label), ":"))
+		value = arabic.Clean(value)
+		if label == "" || value == "" {
+			return
+		}
+		for field, names := range profileLabels {
+			if _, done := fields[field]; done {
+				continue
+			}
+			for _, name := range names {
+				if label == arabic.Normalize(name) {
+					fields[field] = value
+				}
+			}
+		}
+	}
+
+	doc.Find("tr").Each(func(i int, row *goquery.Selection) {
+		cells := row.Find("th, td")
+		if cells.Length() >= 2 {
+			record(cells.Eq(0).Text(), cells.Eq(1).Text())
+		}
+	})
+	doc.Find("dt").Each(func(i int, dt *goquery.Selection) {
+		record(dt.Text(), dt.NextFiltered("dd").Text())
+	})
+
+	if lang == scraper.LangEnglish {
+		c.NameEn = fields["name"]
+	} else {
+		c.NameAr = fields["name"]
+	}
+	c.Sector = sectorID(fields["sector"])
+	c.Status = profileStatus(fields["status"])
+	if n, ok := parseAmount(fields["capital"]); ok {
+		c.PaidUpCapital = n
+	}
+	if n, ok := parseAmount(fields["shares"]); ok {
+		c.SharesOutstanding = n
+	}
+	if v, ok := fields["listing"]; ok {
+		for _, layout := range profileDateLayouts {
+			if t, err := arabic.ParseDate(layout, strings.Fields(v)[0]); err == nil {
+				c.ListingDate = t.Format("2006-01-02")
+				break
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// parseAmount reads the first number in a value such as
+// "150,000,000,000 دينار", ignoring any fraction
+func parseAmount(value string) (int64, bool) {
+	var b strings.Builder
+	for _, r := range arabic.NormalizeDigits(value) {
+		if (r >= '0' && r <= '9') || r == '.' || r == ',' || r == '٬' {
+			b.WriteRune(r)
+		} else if b.Len() > 0 {
+			break
+		}
+	}
+	f, err := arabic.ParseFloat(b.String())
+	if err != nil || f <= 0 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// sectorID maps a sector name from a profile page to a registry sector ID
+func sectorID(name string) string {
+	norm := arabic.Normalize(name)
+	if norm == "" {
+		return ""
+	}
+	for _, s := range sectorNames {
+		if strings.Contains(norm, arabic.Normalize(s.keyword)) {
+			return s.sector
+		}
+	}
+	return ""
+}
+
+// profileStatus maps the status text of a profile page to a listing state
+func profileStatus(text string) string {
+	norm := arabic.Normalize(text)
+	switch {
+	case norm == "":
+		return ""
+	case strings.Contains(norm, "شطب") || strings.Contains(norm, "delist"):
+		return StatusDelisted
+	case strings.Contains(norm, "موقوف") || strings.Contains(norm, "ايقاف") ||
+		strings.Contains(norm, "معلق") || strings.Contains(norm, "suspend"):
+		return StatusSuspended
+	default:
+		return StatusListed
+	}
+}
+
+// ProfileScraper fetches ISX company profile pages into the companies table
+type ProfileScraper struct {
+	Store   *Store
+	Fetcher scraper.PageFetcher
+	// Registry, when set, learns the companies discovered in news once
+	// their profiles are scraped
+	Registry *registry.Registry
+}
+
+// Constructor for the company profile scraper
+func NewProfileScraper(store *Store, fetcher scraper.PageFetcher, reg *registry.Registry) *ProfileScraper {
+	return &ProfileScraper{Store: store, Fetcher: fetcher, Registry: reg}
+}
+
+// Run scrapes the profiles that are missing or out of date, including
+// those of companies tagged on news that were not stored yet. It fails
+// only if no profile could be scraped.
+func (p *ProfileScraper) Run(ctx context.Context) error {
+	discovered, err := p.Store.DiscoverCompanies()
+	if err != nil {
+		return err
+	}
+	if len(discovered) > 0 && p.Registry != nil {
+		defer func() {
+			if err := p.Store.RegisterCompanies(p.Registry); err != nil {
+				log.Printf("Error adding discovered companies to the registry: %v", err)
+			}
+		}()
+	}
+
+	tickers, err := p.Store.staleProfiles(profileMaxAge)
+	if err != nil {
+		return err
+	}
+	if len(tickers) == 0 {
+		log.Println("All company profiles are up to date")
+		return nil
+	}
+
+	log.Printf("Scraping %d company profiles", len(tickers))
+	var failed int
+	var lastErr error
+	for _, ticker := range tickers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := p.ScrapeCompany(ctx, ticker); err != nil {
+			log.Printf("Error scraping profile of %s: %v", ticker, err)
+			failed++
+			lastErr = err
+		}
+	}
+
+	log.Printf("Scraped %d of %d company profiles", len(tickers)-failed, len(tickers))
+	if failed == len(tickers) {
+		return fmt.Errorf("no company profile could be scraped: %w", lastErr)
+	}
+	return nil
+}
+
+// ScrapeCompany fetches the Arabic and English profile pages of one company
+// and stores what they contain, or returns ErrEmptyProfile if they contain
+// none of its details
+func (p *ProfileScraper) ScrapeCompany(ctx context.Context, ticker string) error {
+	ar, arErr := p.fetchProfile(ctx, ticker, scraper.LangArabic)
+	en, enErr := p.fetchProfile(ctx, ticker, scraper.LangEnglish)
+	if arErr != nil && enErr != nil {
+		return arErr
+	}
+
+	// The Arabic page is the reference; the English one adds the English
+	// name and fills any field the Arabic page lacked
+	c := ar
+	c.Ticker = ticker
+	c.NameEn = en.NameEn
+	if c.Sector == "" {
+		c.Sector = en.Sector
+	}
+	if c.ListingDate == "" {
+		c.ListingDate = en.ListingDate
+	}
+	if c.PaidUpCapital == 0 {
+		c.PaidUpCapital = en.PaidUpCapital
+	}
+	if c.SharesOutstanding == 0 {
+		c.SharesOutstanding = en.SharesOutstanding
+	}
+	if c.Status == "" {
+		c.Status = en.Status
+	}
+
+	// Saving marks the profile fresh, so an empty one would put off the
+	// next real scrape
+	if c.NameAr == "" && c.NameEn == "" && c.Sector == "" && c.SharesOutstanding <= 0 {
+		return ErrEmptyProfile
+	}
+
+	return p.Store.SaveCompanyProfile(c)
+}
+
+// fetchProfile loads and parses one profile page
+func (p *ProfileScraper) fetchProfile(ctx context.Context, ticker, lang string) (Company, error) {
+	ctx, cancel := context.WithTimeout(ctx, profilePageTimeout)
+	defer cancel()
+
+	html, err := p.Fetcher.Fetch(ctx, fmt.Sprintf(profileURL, ticker, lang), "")
+	if err != nil {
+		return Company{}, fmt.Errorf("failed to get %s profile page: %w", lang, err)
+	}
+	return ParseCompanyProfile(html, lang)
+}
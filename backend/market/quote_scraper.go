@@ -0,0 +1,64 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"isxportfolio-backend/scraper"
+	"log"
+	"time"
+)
+
+// ISX portal page with the live trading board of the current session
+const liveTradingURL = "http://www.isx-iq.net/isxportal/portal/tradingBoard.html?currLanguage=en"
+
+// Maximum time to spend loading the live trading page
+const quotePageTimeout = time.Minute
+
+// Quote snapshots older than this are deleted
+const quoteRetention = 30 * 24 * time.Hour
+
+// QuoteScraper polls the ISX live trading page into the quotes table and
+// the in-memory quote cache
+type QuoteScraper struct {
+	Store   *Store
+	Fetcher scraper.PageFetcher
+	Cache   *QuoteCache
+}
+
+// Constructor for the quote scraper. The cache starts with the latest
+// stored quotes so they can be served before the first poll.
+func NewQuoteScraper(store *Store, fetcher scraper.PageFetcher, cache *QuoteCache) *QuoteScraper {
+	quotes, err := store.LatestQuotes()
+	if err != nil {
+		log.Printf("Error loading stored quotes: %v", err)
+	}
+	cache.Update(quotes)
+	return &QuoteScraper{Store: store, Fetcher: fetcher, Cache: cache}
+}
+
+// Poll takes one snapshot of the live trading page
+func (q *QuoteScraper) Poll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, quotePageTimeout)
+	defer cancel()
+
+	asOf := time.Now()
+	html, err := q.Fetcher.Fetch(ctx, liveTradingURL, "")
+	if err != nil {
+		return fmt.Errorf("failed to get live trading page: %w", err)
+	}
+	quotes, err := ParseQuotes(html, asOf)
+	if err != nil {
+		return err
+	}
+	if len(quotes) == 0 {
+		return fmt.Errorf("no quotes found on the live trading page")
+	}
+
+	if err := q.Store.SaveQuotes(quotes); err != nil {
+		return err
+	}
+	q.Cache.Update(quotes)
+	log.Printf("Stored %d live quotes", len(quotes))
+
+	return q.Store.PruneQuotes(asOf.Add(-quoteRetention))
+}
@@ -0,0 +1,207 @@
+package market
+
+import (
+	"fmt"
+	"isxportfolio-backend/arabic"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Quote is one ticker's live trading figures at a point in a session
+type Quote struct {
+	Ticker        string    `json:"ticker"`
+	Last          float64   `json:"last"`
+	Change        float64   `json:"change"`         // from the previous close
+	ChangePercent float64   `json:"change_percent"` // from the previous close
+	Bid           float64   `json:"bid"`
+	Ask           float64   `json:"ask"`
+	Volume        int64     `json:"volume"` // shares traded so far in the session
+	AsOf          time.Time `json:"as_of"`
+}
+
+// Column headers of the ISX live trading table in Arabic and English
+var quoteColumns = map[string][]string{
+	"ticker":         {"رمز الشركه", "الرمز", "code", "symbol", "company code"},
+	"last":           {"اخر سعر", "سعر اخر صفقه", "السعر الحالي", "last price", "last", "last trade price"},
+	"change":         {"التغير", "مقدار التغير", "change"},
+	"change_percent": {"نسبه التغير", "التغير %", "% التغير", "change %", "% change", "change percent"},
+	"bid":            {"افضل طلب شراء", "سعر الطلب", "الطلب", "bid", "best bid", "bid price"},
+	"ask":            {"افضل عرض بيع", "سعر العرض", "العرض", "ask", "best ask", "ask price", "offer"},
+	"volume":         {"عدد الاسهم المتداوله", "الاسهم المتداوله", "traded shares", "no. of shares", "volume"},
+}
+
+// ParseQuotes reads the live trading table, stamping every quote with
+// asOf. Rows without a ticker or last price are skipped.
+func ParseQuotes(html string, asOf time.Time) ([]Quote, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse live quotes: %w", err)
+	}
+
+	var quotes []Quote
+	doc.Find("table").Each(func(i int, table *goquery.Selection) {
+		columns := map[string]int{}
+		table.Find("tr").Each(func(j int, row *goquery.Selection) {
+			cells := row.Find("th, td")
+			if len(columns) == 0 {
+				columns = tableHeader(cells, quoteColumns, "last")
+				return
+			}
+
+			text := func(field string) string {
+				col, ok := columns[field]
+				if !ok || col >= cells.Length() {
+					return ""
+				}
+				return arabic.Clean(cells.Eq(col).Text())
+			}
+
+			q := Quote{
+				Ticker: strings.ToUpper(text("ticker")),
+				Last:   parsePrice(text("last")),
+				Bid:    parsePrice(text("bid")),
+				Ask:    parsePrice(text("ask")),
+				AsOf:   asOf,
+			}
+			// Changes can be negative, unlike the other figures
+			q.Change, _ = arabic.ParseFloat(text("change"))
+			q.ChangePercent, _ = arabic.ParseFloat(strings.TrimSuffix(text("change_percent"), "%"))
+			q.Volume, _ = parseAmount(text("volume"))
+			if q.Ticker == "" || q.Last <= 0 {
+				return
+			}
+			quotes = append(quotes, q)
+		})
+	})
+
+	return quotes, nil
+}
+
+// SaveQuotes stores a snapshot of live quotes
+func (s *Store) SaveQuotes(quotes []Quote) error {
+	if len(quotes) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO quotes (ticker, as_of, last, change, change_percent, bid, ask, volume)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("error preparing quote statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, q := range quotes {
+		if _, err := stmt.Exec(strings.ToUpper(q.Ticker), q.AsOf.UTC(), q.Last, q.Change, q.ChangePercent,
+			q.Bid, q.Ask, q.Volume); err != nil {
+			return fmt.Errorf("error saving quote of %s: %w", q.Ticker, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing quotes: %w", err)
+	}
+	return nil
+}
+
+// LatestQuotes returns the most recent stored quote of every ticker
+func (s *Store) LatestQuotes() ([]Quote, error) {
+	rows, err := s.db.Query(`
+		SELECT q.ticker, q.as_of, q.last, q.change, q.change_percent, q.bid, q.ask, q.volume
+		FROM quotes q
+		JOIN (SELECT ticker, MAX(as_of) AS as_of FROM quotes GROUP BY ticker) latest
+			ON latest.ticker = q.ticker AND latest.as_of = q.as_of
+		ORDER BY q.ticker`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying latest quotes: %w", err)
+	}
+	defer rows.Close()
+
+	quotes := []Quote{}
+	for rows.Next() {
+		var q Quote
+		if err := rows.Scan(&q.Ticker, &q.AsOf, &q.Last, &q.Change, &q.ChangePercent,
+			&q.Bid, &q.Ask, &q.Volume); err != nil {
+			return nil, fmt.Errorf("error scanning quote: %w", err)
+		}
+		quotes = append(quotes, q)
+	}
+	return quotes, rows.Err()
+}
+
+// PruneQuotes deletes quote snapshots taken before the given time
+func (s *Store) PruneQuotes(before time.Time) error {
+	res, err := s.db.Exec("DELETE FROM quotes WHERE as_of < ?", before.UTC())
+	if err != nil {
+		return fmt.Errorf("error pruning quotes: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("Pruned %d old quote snapshots", n)
+	}
+	return nil
+}
+
+// QuoteCache holds the latest quote of every ticker in memory
+type QuoteCache struct {
+	mu     sync.RWMutex
+	quotes map[string]Quote
+}
+
+// Constructor for the quote cache
+func NewQuoteCache() *QuoteCache {
+	return &QuoteCache{quotes: make(map[string]Quote)}
+}
+
+// Update replaces the cached quotes of the given tickers. Tickers missing
+// from a poll keep their previous quote.
+func (c *QuoteCache) Update(quotes []Quote) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, q := range quotes {
+		c.quotes[q.Ticker] = q
+	}
+}
+
+// Get returns the cached quotes of the given tickers, or of every ticker
+// if none are given, sorted by ticker, along with the time of the newest
+// of those quotes. Tickers without a quote are left out.
+func (c *QuoteCache) Get(tickers []string) ([]Quote, time.Time) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	quotes := []Quote{}
+	if len(tickers) == 0 {
+		for _, q := range c.quotes {
+			quotes = append(quotes, q)
+		}
+	} else {
+		seen := make(map[string]bool)
+		for _, t := range tickers {
+			t = strings.ToUpper(t)
+			if q, ok := c.quotes[t]; ok && !seen[t] {
+				seen[t] = true
+				quotes = append(quotes, q)
+			}
+		}
+	}
+	sort.Slice(quotes, func(i, j int) bool { return quotes[i].Ticker < quotes[j].Ticker })
+
+	var asOf time.Time
+	for _, q := range quotes {
+		if q.AsOf.After(asOf) {
+			asOf = q.AsOf
+		}
+	}
+	return quotes, asOf
+}
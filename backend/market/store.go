@@ -0,0 +1,21 @@
+// Package market keeps ISX market data that is not news, such as listed
+// company profiles and daily prices, and scrapes it from the ISX website.
+package market
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// ErrNotFound is returned when a company or other market record does not exist
+var ErrNotFound = errors.New("not found")
+
+// Store persists market data in SQLite
+type Store struct {
+	db *sql.DB
+}
+
+// Constructor for the market data store
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
@@ -0,0 +1,250 @@
+// Package registry lists the companies traded on the Iraq Stock Exchange.
+// It is used to check the tickers found in news and to recognize companies
+// mentioned by name in news titles. The list ships as a JSON data file.
+package registry
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"isxportfolio-backend/arabic"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:embed companies.json
+var defaultCompanies []byte
+
+// Form of an ISX trading symbol. Some symbols contain digits, as the
+// company codes of ISX profile links may.
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9]{3,6}$`)
+
+// Single-letter Arabic prefixes that may be attached to a company name,
+// as in "لمصرف بغداد" or "وفندق بابل"
+const namePrefixes = "ولبفك"
+
+// Company is a listed company
+type Company struct {
+	Symbol  string   `json:"symbol"`
+	NameAr  string   `json:"name_ar"`
+	NameEn  string   `json:"name_en"`
+	Sector  string   `json:"sector"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// name is a normalized company name or alias
+type name struct {
+	text   string
+	symbol string
+}
+
+// Registry holds the listed companies. Companies can be added while it is
+// in use.
+type Registry struct {
+	mu        sync.RWMutex
+	companies []Company
+	bySymbol  map[string]int
+	names     []name // longest first, so full names win over their aliases
+}
+
+// Load reads the company list from path, or the built-in list when path is
+// empty
+func Load(path string) (*Registry, error) {
+	data := defaultCompanies
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("error reading company registry: %w", err)
+		}
+	}
+
+	var file struct {
+		Companies []Company `json:"companies"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing company registry: %w", err)
+	}
+
+	r := &Registry{bySymbol: make(map[string]int)}
+	for _, c := range file.Companies {
+		c.Symbol = strings.ToUpper(strings.TrimSpace(c.Symbol))
+		if !ValidSymbol(c.Symbol) {
+			return nil, fmt.Errorf("invalid company symbol %q", c.Symbol)
+		}
+		if _, dup := r.bySymbol[c.Symbol]; dup {
+			return nil, fmt.Errorf("duplicate company symbol %s", c.Symbol)
+		}
+		r.add(c)
+	}
+	r.sortNames()
+
+	return r, nil
+}
+
+// ValidSymbol reports whether symbol has the form of an ISX trading symbol
+func ValidSymbol(symbol string) bool {
+	return symbolPattern.MatchString(symbol)
+}
+
+// Add adds a company that is not in the registry yet, such as one found in
+// the companies table, and reports whether it was added
+func (r *Registry) Add(c Company) bool {
+	c.Symbol = strings.ToUpper(strings.TrimSpace(c.Symbol))
+	if !ValidSymbol(c.Symbol) {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.bySymbol[c.Symbol]; ok {
+		return false
+	}
+	r.add(c)
+	r.sortNames()
+	return true
+}
+
+// add appends a company and its names. Must be called with r.mu held or
+// before r is shared.
+func (r *Registry) add(c Company) {
+	r.bySymbol[c.Symbol] = len(r.companies)
+	r.companies = append(r.companies, c)
+
+	for _, n := range append([]string{c.NameAr, c.NameEn}, c.Aliases...) {
+		if text := arabic.Normalize(n); text != "" {
+			r.names = append(r.names, name{text: text, symbol: c.Symbol})
+		}
+	}
+}
+
+// sortNames puts the longest names first, so full names win over their
+// aliases
+func (r *Registry) sortNames() {
+	sort.SliceStable(r.names, func(i, j int) bool { return len(r.names[i].text) > len(r.names[j].text) })
+}
+
+// LoadFromEnv loads the company list named by ISX_COMPANIES_FILE, falling
+// back to the built-in list
+func LoadFromEnv() *Registry {
+	path := os.Getenv("ISX_COMPANIES_FILE")
+	r, err := Load(path)
+	if err != nil && path != "" {
+		log.Printf("Error loading company registry from %s, using built-in list: %v", path, err)
+		r, err = Load("")
+	}
+	if err != nil {
+		log.Fatalf("Built-in company registry is invalid: %v", err)
+	}
+	return r
+}
+
+// Companies lists the listed companies in file order, followed by those
+// added later
+func (r *Registry) Companies() []Company {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Company(nil), r.companies...)
+}
+
+// Company looks up a listed company by symbol
+func (r *Registry) Company(symbol string) (Company, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	i, ok := r.bySymbol[strings.ToUpper(symbol)]
+	if !ok {
+		return Company{}, false
+	}
+	return r.companies[i], true
+}
+
+// IsListed reports whether symbol belongs to a listed company
+func (r *Registry) IsListed(symbol string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.bySymbol[strings.ToUpper(symbol)]
+	return ok
+}
+
+// MatchTitle returns the symbols of the companies named in a news title,
+// in the order they appear. Names only match whole words, and a name that
+// is part of a longer matched name is not counted again.
+func (r *Registry) MatchTitle(title string) []string {
+	text := []rune(arabic.Normalize(title))
+	if len(text) == 0 {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type match struct {
+		pos    int
+		symbol string
+	}
+	var matches []match
+	seen := make(map[string]bool)
+	for _, n := range r.names {
+		pattern := []rune(n.text)
+		for pos := indexRunes(text, pattern, 0); pos >= 0; pos = indexRunes(text, pattern, pos+1) {
+			if !atWordStart(text, pos) || !atWordEnd(text, pos+len(pattern)) {
+				continue
+			}
+			if !seen[n.symbol] {
+				seen[n.symbol] = true
+				matches = append(matches, match{pos: pos, symbol: n.symbol})
+			}
+			// Blank out the match so shorter names inside it do not match
+			for i := pos; i < pos+len(pattern); i++ {
+				text[i] = 0
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].pos < matches[j].pos })
+	symbols := make([]string, len(matches))
+	for i, m := range matches {
+		symbols[i] = m.symbol
+	}
+	return symbols
+}
+
+// indexRunes returns the first index of pattern in text at or after from,
+// or -1
+func indexRunes(text, pattern []rune, from int) int {
+	for i := from; i+len(pattern) <= len(text); i++ {
+		found := true
+		for j, r := range pattern {
+			if text[i+j] != r {
+				found = false
+				break
+			}
+		}
+		if found {
+			return i
+		}
+	}
+	return -1
+}
+
+// atWordStart reports whether a name starting at pos begins a word,
+// allowing for one attached prefix letter
+func atWordStart(text []rune, pos int) bool {
+	if pos == 0 || isBoundary(text[pos-1]) {
+		return true
+	}
+	return strings.ContainsRune(namePrefixes, text[pos-1]) && (pos == 1 || isBoundary(text[pos-2]))
+}
+
+// atWordEnd reports whether a name ending at end finishes a word
+func atWordEnd(text []rune, end int) bool {
+	return end == len(text) || isBoundary(text[end])
+}
+
+// isBoundary reports whether r separates words
+func isBoundary(r rune) bool {
+	return r == ' ' || strings.ContainsRune(`.,:;!?()[]"'«»/-،؛؟`, r)
+}
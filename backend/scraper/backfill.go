@@ -0,0 +1,255 @@
+package scraper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Link texts used by the story list pagination for the next page
+var nextPageTexts = map[string]bool{
+	"التالي": true,
+	"next":   true,
+	"»":      true,
+	"›":      true,
+	">":      true,
+	">>":     true,
+}
+
+// Times a story list page is scraped before a backfill gives up on it
+const backfillPageAttempts = 3
+
+// errIncompletePage means some new items on a backfilled page could not be
+// processed, so the page has to be scraped again
+var errIncompletePage = errors.New("items on the page could not be processed")
+
+// BackfillCheckpoint records how far a backfill has walked one story list
+type BackfillCheckpoint struct {
+	ListURL    string
+	NextURL    string
+	Until      string // YYYY-MM-DD the backfill walks back to
+	Pages      int
+	OldestDate time.Time
+	Done       bool
+}
+
+// ParseNextPageURL returns the absolute URL of the next story list page,
+// or "" on the last page
+func ParseNextPageURL(html, pageURL string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse pagination: %w", err)
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid page URL: %w", err)
+	}
+
+	var next string
+	doc.Find(".pagelinks a, .pagination a, .paging a").EachWithBreak(func(i int, a *goquery.Selection) bool {
+		text := strings.ToLower(strings.TrimSpace(a.Text()))
+		if !nextPageTexts[text] {
+			return true
+		}
+		href, ok := a.Attr("href")
+		if !ok || href == "" || strings.HasPrefix(href, "javascript:") {
+			return true
+		}
+		ref, err := url.Parse(href)
+		if err != nil {
+			return true
+		}
+		next = base.ResolveReference(ref).String()
+		return false
+	})
+
+	return next, nil
+}
+
+// Backfill walks each story list back page by page until it reaches items
+// published before until. Progress is checkpointed after every page whose
+// items were all stored, so an interrupted backfill with the same until
+// date resumes where it stopped.
+// Each page gets at most RunTimeout within ctx.
+func (s *MarketNewsScraper) Backfill(ctx context.Context, until time.Time) error {
+	log.Printf("=== Starting Market News Backfill until %s ===", until.Format("2006-01-02"))
+
+	for _, listURL := range newsURLs {
+		if err := s.backfillList(ctx, listURL, until); err != nil {
+			return fmt.Errorf("error backfilling %s: %w", listURL, err)
+		}
+	}
+
+	log.Println("=== Market News Backfill Finished ===")
+	return nil
+}
+
+// backfillList walks a single story list
+func (s *MarketNewsScraper) backfillList(ctx context.Context, listURL string, until time.Time) error {
+	untilStr := until.Format("2006-01-02")
+
+	cp, err := s.Store.LoadCheckpoint(listURL)
+	if err != nil {
+		return err
+	}
+	if cp == nil || cp.Until != untilStr {
+		cp = &BackfillCheckpoint{ListURL: listURL, NextURL: listURL, Until: untilStr}
+	} else if cp.Done {
+		log.Printf("Backfill of %s until %s already finished", listURL, untilStr)
+		return nil
+	} else {
+		log.Printf("Resuming backfill of %s at page %d: %s", listURL, cp.Pages+1, cp.NextURL)
+	}
+
+	for cp.NextURL != "" {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("backfill stopped at page %d: %w", cp.Pages+1, err)
+		}
+		pageURL := cp.NextURL
+		oldest, nextURL, err := s.backfillPage(ctx, pageURL, listLanguage(listURL))
+		// Items already stored are skipped on a retry, so only the failed
+		// ones are fetched again
+		for attempt := 2; errors.Is(err, errIncompletePage) && attempt <= backfillPageAttempts && ctx.Err() == nil; attempt++ {
+			log.Printf("Retrying backfill page %s (attempt %d): %v", pageURL, attempt, err)
+			oldest, nextURL, err = s.backfillPage(ctx, pageURL, listLanguage(listURL))
+		}
+		if err != nil {
+			// The checkpoint still points at this page, so a resumed
+			// backfill starts with it again
+			return err
+		}
+
+		cp.Pages++
+		cp.NextURL = nextURL
+		if !oldest.IsZero() && (cp.OldestDate.IsZero() || oldest.Before(cp.OldestDate)) {
+			cp.OldestDate = oldest
+		}
+		if nextURL == "" || (!oldest.IsZero() && oldest.Before(until)) {
+			cp.Done = true
+			cp.NextURL = ""
+		}
+
+		if err := s.Store.SaveCheckpoint(cp); err != nil {
+			return err
+		}
+		s.reportProgress("backfill", cp.Pages, 0)
+		log.Printf("Backfilled page %d of %s, oldest item %s", cp.Pages, listURL, cp.OldestDate.Format("2006-01-02"))
+	}
+
+	return nil
+}
+
+// backfillPage scrapes one story list page in lang, stores its items and
+// returns the oldest item date on the page along with the next page URL
+func (s *MarketNewsScraper) backfillPage(ctx context.Context, pageURL, lang string) (time.Time, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.RunTimeout)
+	defer cancel()
+
+	fetchCtx, cancelFetch := context.WithTimeout(ctx, pageTimeout)
+	html, err := s.Fetcher.Fetch(fetchCtx, pageURL, newsRowSelector)
+	cancelFetch()
+	if err != nil {
+		s.Stats.recordListError()
+		return time.Time{}, "", fmt.Errorf("failed to get page %s: %w", pageURL, err)
+	}
+
+	items, list, err := parseNewsList(html)
+	if err != nil {
+		s.Stats.recordListError()
+		return time.Time{}, "", err
+	}
+	s.Stats.recordList(list)
+	setLanguage(items, lang)
+	nextURL, err := ParseNextPageURL(html, pageURL)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	links := make([]string, len(items))
+	for i, item := range items {
+		links[i] = item.Link
+	}
+	existing, err := s.Store.FindByLinks(links)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("error reading stored news items: %w", err)
+	}
+
+	s.ExistingItems = existing
+	s.AllItems = s.mergeNewsItems(existing, items)
+	failed, processErr := s.processNewsItems(ctx)
+
+	// Keep what was processed even when the page is incomplete
+	s.classifyItems()
+	s.tagTickers()
+	if err := s.Store.SaveItems(s.AllItems); err != nil {
+		return time.Time{}, "", fmt.Errorf("error saving backfilled items: %w", err)
+	}
+	if processErr != nil {
+		return time.Time{}, "", processErr
+	}
+	if failed > 0 {
+		return time.Time{}, "", fmt.Errorf("%w: %d of them on %s", errIncompletePage, failed, pageURL)
+	}
+
+	var oldest time.Time
+	for _, item := range items {
+		if t, err := parseDateTime(item.Date); err == nil && (oldest.IsZero() || t.Before(oldest)) {
+			oldest = t
+		}
+	}
+
+	return oldest, nextURL, nil
+}
+
+// LoadCheckpoint returns the backfill checkpoint for a story list, or nil
+// if none has been saved
+func (s *NewsStore) LoadCheckpoint(listURL string) (*BackfillCheckpoint, error) {
+	cp := BackfillCheckpoint{ListURL: listURL}
+	var oldest sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT next_url, until_date, pages, oldest_date, done
+		FROM news_backfill_checkpoints
+		WHERE list_url = ?`, listURL).Scan(&cp.NextURL, &cp.Until, &cp.Pages, &oldest, &cp.Done)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading backfill checkpoint: %w", err)
+	}
+	if oldest.Valid {
+		cp.OldestDate = oldest.Time
+	}
+	return &cp, nil
+}
+
+// SaveCheckpoint stores the backfill checkpoint for a story list
+func (s *NewsStore) SaveCheckpoint(cp *BackfillCheckpoint) error {
+	var oldest interface{}
+	if !cp.OldestDate.IsZero() {
+		oldest = cp.OldestDate.Format(dbTimeLayout)
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO news_backfill_checkpoints (list_url, next_url, until_date, pages, oldest_date, done, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(list_url) DO UPDATE SET
+			next_url = excluded.next_url,
+			until_date = excluded.until_date,
+			pages = excluded.pages,
+			oldest_date = excluded.oldest_date,
+			done = excluded.done,
+			updated_at = CURRENT_TIMESTAMP`,
+		cp.ListURL, cp.NextURL, cp.Until, cp.Pages, oldest, cp.Done)
+	if err != nil {
+		return fmt.Errorf("error saving backfill checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package scraper
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// Number of finished runs kept in memory for status lookups
+const maxRunHistory = 50
+
+// RunStatus describes where a scraper run is in its lifecycle
+type RunStatus string
+
+const (
+	RunRunning   RunStatus = "running"
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+)
+
+// Kinds of scraper runs
+const (
+	RunKindUpdate   = "update"
+	RunKindBackfill = "backfill"
+)
+
+// RunInfo is a point in time view of a scraper run
+type RunInfo struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	Trigger    string     `json:"trigger"`
+	Status     RunStatus  `json:"status"`
+	Phase      string     `json:"phase"`
+	Processed  int        `json:"processed"`
+	Total      int        `json:"total"`
+	NewItems   int        `json:"new_items"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Run tracks a single execution of the market news scraper
+type Run struct {
+	mu     sync.Mutex
+	info   RunInfo
+	err    error
+	done   chan struct{}
+	cancel context.CancelFunc
+}
+
+// Info returns a copy of the run's current state
+func (r *Run) Info() RunInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.info
+}
+
+// Wait blocks until the run finishes and returns its error
+func (r *Run) Wait() error {
+	<-r.done
+	return r.err
+}
+
+// Done is closed when the run finishes
+func (r *Run) Done() <-chan struct{} {
+	return r.done
+}
+
+// Cancel asks the run to stop. It returns false if the run had already
+// finished.
+func (r *Run) Cancel() bool {
+	select {
+	case <-r.done:
+		return false
+	default:
+	}
+	log.Printf("Cancelling scraper run %s", r.Info().ID)
+	r.cancel()
+	return true
+}
+
+func (r *Run) setProgress(phase string, processed, total int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.info.Phase = phase
+	r.info.Processed = processed
+	r.info.Total = total
+}
+
+func (r *Run) finish(newItems int, err error) {
+	r.mu.Lock()
+	now := time.Now()
+	r.info.FinishedAt = &now
+	r.info.NewItems = newItems
+	if err != nil {
+		r.info.Status = RunFailed
+		r.info.Error = err.Error()
+	} else {
+		r.info.Status = RunSucceeded
+	}
+	r.err = err
+	r.mu.Unlock()
+	r.cancel()
+	close(r.done)
+}
+
+// RunCoordinator owns the shared scraper and makes sure only one run
+// happens at a time. Run requests that arrive while a run is in progress
+// join the existing run instead of starting another one.
+type RunCoordinator struct {
+	scraper *MarketNewsScraper
+	broker  *NewsBroker
+
+	mu      sync.Mutex
+	current *Run
+	runs    map[string]*Run
+	order   []string
+}
+
+// Constructor for the run coordinator. New items found by update runs are
+// published to broker when it is not nil.
+func NewRunCoordinator(s *MarketNewsScraper, broker *NewsBroker) *RunCoordinator {
+	return &RunCoordinator{
+		scraper: s,
+		broker:  broker,
+		runs:    make(map[string]*Run),
+	}
+}
+
+// Trigger starts a scraper run, or returns the run already in progress.
+// started reports whether a new run was started.
+func (c *RunCoordinator) Trigger(trigger string) (run *Run, started bool) {
+	return c.start(trigger, RunKindUpdate, c.scraper.Run)
+}
+
+// TriggerBackfill starts a backfill back to until. Like Trigger, it returns
+// the run in progress instead if there is one, whatever its kind.
+func (c *RunCoordinator) TriggerBackfill(trigger string, until time.Time) (run *Run, started bool) {
+	return c.start(trigger, RunKindBackfill, func(ctx context.Context) error {
+		return c.scraper.Backfill(ctx, until)
+	})
+}
+
+// start runs fn in the background with a context that is cancelled by
+// Run.Cancel
+func (c *RunCoordinator) start(trigger, kind string, fn func(ctx context.Context) error) (run *Run, started bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.current != nil {
+		log.Printf("Scraper run %s already in progress, joining it (%s)", c.current.info.ID, trigger)
+		return c.current, false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run = &Run{
+		info: RunInfo{
+			ID:        newRunID(),
+			Kind:      kind,
+			Trigger:   trigger,
+			Status:    RunRunning,
+			Phase:     "starting",
+			StartedAt: time.Now(),
+		},
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+	c.current = run
+	c.remember(run)
+
+	go c.execute(ctx, run, fn)
+	return run, true
+}
+
+// Get looks up a current or recent run by ID
+func (c *RunCoordinator) Get(id string) (*Run, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	run, ok := c.runs[id]
+	return run, ok
+}
+
+func (c *RunCoordinator) execute(ctx context.Context, run *Run, fn func(ctx context.Context) error) {
+	log.Printf("Starting %s scraper run %s (%s)", run.info.Kind, run.info.ID, run.info.Trigger)
+
+	stats := &ExtractionStats{}
+	c.scraper.OnProgress = run.setProgress
+	c.scraper.Stats = stats
+	err := safeRun(ctx, fn)
+	c.scraper.OnProgress = nil
+	c.scraper.Stats = nil
+
+	var newItems []NewsItem
+	for _, item := range c.scraper.AllItems {
+		// Items without an ID were not saved and will be found again
+		if item.IsNew && item.ID != 0 {
+			newItems = append(newItems, item)
+		}
+	}
+
+	// Backfilled items are old news, so only update runs are pushed live
+	if c.broker != nil && run.info.Kind == RunKindUpdate {
+		c.broker.Publish(newItems)
+	}
+
+	c.mu.Lock()
+	c.current = nil
+	c.mu.Unlock()
+
+	run.finish(len(newItems), err)
+	if err != nil {
+		log.Printf("Scraper run %s failed: %v", run.info.ID, err)
+	} else {
+		log.Printf("Scraper run %s finished, %d new items", run.info.ID, len(newItems))
+	}
+
+	health := newScrapeRun(run.Info(), stats)
+	if err := c.scraper.Store.SaveScrapeRun(&health); err != nil {
+		log.Printf("Error saving health of scraper run %s: %v", run.info.ID, err)
+	}
+}
+
+// safeRun runs the scraper, turning a panic into an error so the
+// coordinator is never left with a run that does not finish
+func safeRun(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Scraper panicked: %v", r)
+			err = errors.New("scraper panicked")
+		}
+	}()
+	return fn(ctx)
+}
+
+// remember records a run, dropping the oldest ones beyond maxRunHistory.
+// Must be called with c.mu held.
+func (c *RunCoordinator) remember(run *Run) {
+	c.runs[run.info.ID] = run
+	c.order = append(c.order, run.info.ID)
+	for len(c.order) > maxRunHistory {
+		delete(c.runs, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// newRunID returns a random identifier for a run
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,100 @@
+package scraper
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// ImportCSVOnce loads a market_news.csv written by older versions of the
+// scraper into the store, then renames the file so it is not imported again
+func (s *NewsStore) ImportCSVOnce(csvPath, baseURL string) error {
+	if _, err := os.Stat(csvPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	log.Printf("Importing legacy news CSV: %s", csvPath)
+	items, err := ReadNewsCSV(csvPath, baseURL)
+	if err != nil {
+		return fmt.Errorf("error reading CSV: %w", err)
+	}
+
+	if err := s.SaveItems(items); err != nil {
+		return fmt.Errorf("error importing CSV: %w", err)
+	}
+
+	importedPath := csvPath + ".imported"
+	if err := os.Rename(csvPath, importedPath); err != nil {
+		return fmt.Errorf("error renaming imported CSV: %w", err)
+	}
+
+	log.Printf("Imported %d news items, CSV moved to %s", len(items), importedPath)
+	return nil
+}
+
+// ReadNewsCSV parses a market_news.csv file. Attachments are stored in the
+// last column as "url|filename|isLoaded" entries separated by ";".
+func ReadNewsCSV(csvPath, baseURL string) ([]NewsItem, error) {
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	// Skip header
+	if _, err := reader.Read(); err != nil {
+		return nil, fmt.Errorf("error reading header: %w", err)
+	}
+
+	linkPrefix := baseURL + "/isxportal/portal/"
+
+	var items []NewsItem
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			// Skip corrupted rows instead of giving up on the whole file
+			log.Printf("Skipping unreadable CSV row %d: %v", line, err)
+			continue
+		}
+		if len(record) < 6 {
+			continue
+		}
+
+		item := NewsItem{
+			Date:   strings.TrimSpace(record[0] + " " + record[1]),
+			Title:  record[2],
+			Link:   strings.TrimPrefix(record[3], linkPrefix),
+			Ticker: record[4],
+		}
+		if item.Link == "" {
+			continue
+		}
+
+		if len(record) > 6 && record[6] != "" {
+			for _, attStr := range strings.Split(record[6], ";") {
+				parts := strings.Split(attStr, "|")
+				if len(parts) == 3 {
+					item.Attachments = append(item.Attachments, Attachment{
+						URL:      parts[0],
+						Filename: parts[1],
+						IsLoaded: parts[2] == "true",
+					})
+				}
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
@@ -0,0 +1,253 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Defaults for attachment downloads
+const (
+	defaultDownloadTimeout = 2 * time.Minute
+	defaultDownloadRetries = 3
+	defaultDownloadBackoff = 2 * time.Second
+	defaultDownloadMaxMB   = 50
+)
+
+// The PDF header must start within this many bytes of the file
+const pdfMagicWindow = 1024
+
+var pdfMagic = []byte("%PDF-")
+
+var (
+	// ErrNotPDF is returned when a download is not a PDF, such as an HTML error page
+	ErrNotPDF = errors.New("response is not a PDF")
+	// ErrTooLarge is returned when a download exceeds the size limit
+	ErrTooLarge = errors.New("file exceeds the download size limit")
+	// ErrAttachmentMissing is returned when a downloaded file is not on disk
+	ErrAttachmentMissing = errors.New("attachment file is missing")
+	// ErrAttachmentCorrupt is returned when a file on disk does not match its checksum
+	ErrAttachmentCorrupt = errors.New("attachment file is corrupt")
+)
+
+// statusError is a non-200 HTTP response
+type statusError struct {
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return "bad status: " + e.status
+}
+
+// DownloadResult describes a file written by the Downloader
+type DownloadResult struct {
+	Path   string
+	SHA256 string
+	Size   int64
+}
+
+// Downloader fetches attachments to disk. Files are written to a temporary
+// name and renamed once complete, so a partial download is never mistaken
+// for a finished one.
+type Downloader struct {
+	Client   *http.Client
+	Retries  int           // attempts after the first one
+	Backoff  time.Duration // delay before the first retry, doubled each time
+	MaxBytes int64
+}
+
+// Constructor for a downloader configured from the environment
+func NewDownloaderFromEnv() *Downloader {
+	return &Downloader{
+		Client:   &http.Client{Timeout: defaultDownloadTimeout},
+		Retries:  envInt("SCRAPER_DOWNLOAD_RETRIES", defaultDownloadRetries),
+		Backoff:  defaultDownloadBackoff,
+		MaxBytes: int64(envInt("SCRAPER_DOWNLOAD_MAX_MB", defaultDownloadMaxMB)) << 20,
+	}
+}
+
+// Download fetches url into path, retrying transient failures with
+// exponential backoff
+func (d *Downloader) Download(ctx context.Context, url, path string) (*DownloadResult, error) {
+	backoff := d.Backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var result *DownloadResult
+		result, err = d.download(ctx, url, path)
+		if err == nil {
+			return result, nil
+		}
+		if attempt >= d.Retries || !retryable(err) {
+			break
+		}
+
+		log.Printf("Download of %s failed (attempt %d/%d), retrying in %s: %v",
+			url, attempt+1, d.Retries+1, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+
+	return nil, err
+}
+
+// download makes a single attempt at fetching url into path
+func (d *Downloader) download(ctx context.Context, url, path string) (*DownloadResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid download URL: %w", err)
+	}
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{code: resp.StatusCode, status: resp.Status}
+	}
+	if !pdfContentType(resp.Header.Get("Content-Type")) {
+		return nil, fmt.Errorf("%w: content type %s", ErrNotPDF, resp.Header.Get("Content-Type"))
+	}
+	if d.MaxBytes > 0 && resp.ContentLength > d.MaxBytes {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLarge, resp.ContentLength)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.part")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	body := io.Reader(resp.Body)
+	if d.MaxBytes > 0 {
+		body = io.LimitReader(resp.Body, d.MaxBytes+1)
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), body)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+	if d.MaxBytes > 0 && size > d.MaxBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, d.MaxBytes)
+	}
+	if err := checkPDFHeader(tmp.Name()); err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("failed to move file into place: %w", err)
+	}
+
+	return &DownloadResult{
+		Path:   path,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+		Size:   size,
+	}, nil
+}
+
+// retryable reports whether a failed download is worth another attempt
+func retryable(err error) bool {
+	if errors.Is(err, ErrNotPDF) || errors.Is(err, ErrTooLarge) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code >= 500 || se.code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// pdfContentType reports whether a Content-Type header allows a PDF body.
+// The ISX server labels some files as generic binary data.
+func pdfContentType(header string) bool {
+	if header == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "application/pdf", "application/x-pdf", "application/octet-stream", "binary/octet-stream", "application/download", "application/force-download":
+		return true
+	}
+	return false
+}
+
+// checkPDFHeader returns ErrNotPDF unless the file starts with a PDF header
+func checkPDFHeader(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	head := make([]byte, pdfMagicWindow)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if !bytes.Contains(head[:n], pdfMagic) {
+		return fmt.Errorf("%w: missing %%PDF- header", ErrNotPDF)
+	}
+	return nil
+}
+
+// VerifyAttachmentFile checks a downloaded attachment against its recorded
+// size and checksum. Files downloaded before checksums were recorded are
+// checked for a PDF header instead. It returns the file's checksum and size.
+func VerifyAttachmentFile(att Attachment, pdfDir string) (string, int64, error) {
+	path := filepath.Join(pdfDir, filepath.Base(att.Filename))
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", 0, ErrAttachmentMissing
+		}
+		return "", 0, fmt.Errorf("failed to open attachment: %w", err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	if att.SHA256 == "" {
+		if err := checkPDFHeader(path); err != nil {
+			return sum, size, fmt.Errorf("%w: %v", ErrAttachmentCorrupt, err)
+		}
+		return sum, size, nil
+	}
+	if size != att.Size || sum != att.SHA256 {
+		return sum, size, ErrAttachmentCorrupt
+	}
+	return sum, size, nil
+}
@@ -0,0 +1,250 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// PageFetcher loads a page and returns its HTML. waitSelector names an
+// element that must be present before the page counts as loaded; fetchers
+// that do not render JavaScript may ignore it.
+type PageFetcher interface {
+	Fetch(ctx context.Context, url, waitSelector string) (string, error)
+}
+
+// Number of pages fetched in parallel unless SCRAPER_TABS says otherwise
+const defaultConcurrency = 4
+
+// NewPageFetcherFromEnv picks a fetcher based on SCRAPER_FETCHER ("http" or
+// "chrome"). The plain HTTP fetcher is the default since ISX serves the
+// news tables in the initial HTML.
+func NewPageFetcherFromEnv() PageFetcher {
+	switch strings.ToLower(os.Getenv("SCRAPER_FETCHER")) {
+	case "chrome", "chromium", "chromedp":
+		tabs := envInt("SCRAPER_TABS", defaultConcurrency)
+		log.Printf("Using chromedp page fetcher with %d tabs", tabs)
+		return NewChromeFetcher(os.Getenv("CHROME_BIN"), tabs)
+	default:
+		log.Println("Using HTTP page fetcher")
+		return NewHTTPFetcher()
+	}
+}
+
+// HTTPFetcher fetches pages with a plain net/http client
+type HTTPFetcher struct {
+	Client    *http.Client
+	UserAgent string
+}
+
+// Constructor for the HTTP fetcher
+func NewHTTPFetcher() *HTTPFetcher {
+	return &HTTPFetcher{
+		Client:    &http.Client{Timeout: 30 * time.Second},
+		UserAgent: "Mozilla/5.0 (compatible; ISXPortfolio/1.0)",
+	}
+}
+
+// Fetch downloads the page body. waitSelector is ignored.
+func (f *HTTPFetcher) Fetch(ctx context.Context, url, waitSelector string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", f.UserAgent)
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("bad status: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read page: %w", err)
+	}
+
+	return string(body), nil
+}
+
+// ChromeFetcher renders pages in a single long-lived headless Chromium,
+// opening one tab per fetch. At most MaxTabs tabs are open at once and the
+// browser is restarted if it crashes.
+type ChromeFetcher struct {
+	ExecPath string
+	MaxTabs  int
+
+	tabs chan struct{}
+
+	mu            sync.Mutex
+	browserCtx    context.Context
+	cancelBrowser context.CancelFunc
+}
+
+// Constructor for the chromedp fetcher. An empty execPath falls back to
+// /usr/bin/chromium.
+func NewChromeFetcher(execPath string, maxTabs int) *ChromeFetcher {
+	if execPath == "" {
+		execPath = "/usr/bin/chromium"
+	}
+	if maxTabs < 1 {
+		maxTabs = 1
+	}
+	return &ChromeFetcher{
+		ExecPath: execPath,
+		MaxTabs:  maxTabs,
+		tabs:     make(chan struct{}, maxTabs),
+	}
+}
+
+// Fetch opens the page in a new tab, waits for waitSelector if given and
+// returns the rendered HTML
+func (f *ChromeFetcher) Fetch(ctx context.Context, url, waitSelector string) (string, error) {
+	// Wait for a free tab
+	select {
+	case f.tabs <- struct{}{}:
+		defer func() { <-f.tabs }()
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	browserCtx, err := f.browser()
+	if err != nil {
+		return "", err
+	}
+
+	html, err := f.fetchInTab(ctx, browserCtx, url, waitSelector)
+	if err != nil && ctx.Err() == nil && browserDied(browserCtx) {
+		log.Printf("Browser crashed while loading %s, restarting: %v", url, err)
+		f.restart(browserCtx)
+
+		if browserCtx, err = f.browser(); err != nil {
+			return "", err
+		}
+		html, err = f.fetchInTab(ctx, browserCtx, url, waitSelector)
+	}
+
+	return html, err
+}
+
+func (f *ChromeFetcher) fetchInTab(ctx, browserCtx context.Context, url, waitSelector string) (string, error) {
+	tabCtx, cancelTab := chromedp.NewContext(browserCtx)
+	defer cancelTab()
+
+	// Close the tab when the caller gives up
+	stop := context.AfterFunc(ctx, cancelTab)
+	defer stop()
+
+	actions := []chromedp.Action{chromedp.Navigate(url)}
+	if waitSelector != "" {
+		actions = append(actions, chromedp.WaitVisible(waitSelector))
+	}
+
+	var html string
+	actions = append(actions, chromedp.OuterHTML("html", &html))
+
+	if err := chromedp.Run(tabCtx, actions...); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("failed to render page: %w", err)
+	}
+
+	return html, nil
+}
+
+// browser returns the browser context, starting Chromium if needed
+func (f *ChromeFetcher) browser() (context.Context, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.browserCtx != nil && f.browserCtx.Err() == nil {
+		return f.browserCtx, nil
+	}
+
+	log.Printf("Starting Chromium: %s", f.ExecPath)
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("headless", true),
+		chromedp.Flag("no-sandbox", true),
+		chromedp.Flag("disable-dev-shm-usage", true),
+		chromedp.Flag("disable-gpu", true),
+		chromedp.ExecPath(f.ExecPath),
+	)
+
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
+	browserCtx, cancelCtx := chromedp.NewContext(allocCtx)
+
+	// Start the browser now so failures show up here and not in a tab
+	if err := chromedp.Run(browserCtx); err != nil {
+		cancelCtx()
+		cancelAlloc()
+		return nil, fmt.Errorf("failed to start browser: %w", err)
+	}
+
+	f.browserCtx = browserCtx
+	f.cancelBrowser = func() {
+		cancelCtx()
+		cancelAlloc()
+	}
+	return f.browserCtx, nil
+}
+
+// browserDied reports whether the browser behind browserCtx is gone
+func browserDied(browserCtx context.Context) bool {
+	if browserCtx.Err() != nil {
+		return true
+	}
+	c := chromedp.FromContext(browserCtx)
+	return c == nil || c.Browser == nil
+}
+
+// restart shuts down the browser behind browserCtx so the next fetch
+// starts a new one. It does nothing if another tab already restarted it.
+func (f *ChromeFetcher) restart(browserCtx context.Context) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.browserCtx != browserCtx {
+		return
+	}
+	f.shutdown()
+}
+
+// Close shuts down the browser
+func (f *ChromeFetcher) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.shutdown()
+	return nil
+}
+
+// shutdown cancels the browser. Must be called with f.mu held.
+func (f *ChromeFetcher) shutdown() {
+	if f.cancelBrowser != nil {
+		f.cancelBrowser()
+	}
+	f.browserCtx = nil
+	f.cancelBrowser = nil
+}
+
+// envInt reads a positive integer from the environment, falling back to def
+func envInt(name string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
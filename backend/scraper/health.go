@@ -0,0 +1,331 @@
+package scraper
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Thresholds used to mark a scraper run as degraded
+const (
+	// Number of earlier healthy runs a run is compared with
+	healthBaselineRuns = 10
+	// A run is degraded when it lists fewer rows than this share of the
+	// baseline median
+	rowDropRatio = 0.5
+	// Share of rows missing a date, title or link that counts as broken
+	fieldFailureRatio = 0.5
+	// Increase over the baseline share of detail pages without a ticker
+	// that counts as broken. Market-wide news has no ticker, so some
+	// misses are normal.
+	tickerFailureIncrease = 0.4
+	// Detail pages needed before the ticker share is judged
+	minTickerSample = 5
+)
+
+// ExtractionStats counts what a scraper run managed to extract. It is safe
+// for concurrent use and a nil *ExtractionStats ignores all records.
+type ExtractionStats struct {
+	mu sync.Mutex
+
+	ListPages      int
+	EmptyListPages int
+	ListErrors     int
+	Rows           int
+	Items          int
+	MissingDate    int
+	MissingTitle   int
+	MissingLink    int
+	DetailPages    int
+	DetailErrors   int
+	MissingTicker  int
+}
+
+// recordList adds the result of parsing one story list page
+func (st *ExtractionStats) recordList(list listStats) {
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.ListPages++
+	if list.Rows == 0 {
+		st.EmptyListPages++
+	}
+	st.Rows += list.Rows
+	st.Items += list.Items
+	st.MissingDate += list.MissingDate
+	st.MissingTitle += list.MissingTitle
+	st.MissingLink += list.MissingLink
+}
+
+// recordListError counts a story list page that could not be loaded
+func (st *ExtractionStats) recordListError() {
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.ListPages++
+	st.ListErrors++
+}
+
+// recordDetail adds the result of loading one detail page
+func (st *ExtractionStats) recordDetail(item NewsItem, err error) {
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.DetailPages++
+	if err != nil {
+		st.DetailErrors++
+	} else if item.Ticker == "" {
+		st.MissingTicker++
+	}
+}
+
+// ScrapeRun is the health record of one scraper run
+type ScrapeRun struct {
+	ID             int64     `json:"id"`
+	RunID          string    `json:"run_id"`
+	Kind           string    `json:"kind"`
+	Status         RunStatus `json:"status"`
+	StartedAt      time.Time `json:"started_at"`
+	DurationMS     int64     `json:"duration_ms"`
+	ListPages      int       `json:"list_pages"`
+	EmptyListPages int       `json:"empty_list_pages"`
+	ListErrors     int       `json:"list_errors"`
+	Rows           int       `json:"rows"`
+	Items          int       `json:"items"`
+	NewItems       int       `json:"new_items"`
+	DetailPages    int       `json:"detail_pages"`
+	DetailErrors   int       `json:"detail_errors"`
+	MissingDate    int       `json:"missing_date"`
+	MissingTitle   int       `json:"missing_title"`
+	MissingLink    int       `json:"missing_link"`
+	MissingTicker  int       `json:"missing_ticker"`
+	Degraded       bool      `json:"degraded"`
+	Reasons        []string  `json:"degraded_reasons"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// newScrapeRun builds the health record of a finished run
+func newScrapeRun(info RunInfo, stats *ExtractionStats) ScrapeRun {
+	run := ScrapeRun{
+		RunID:     info.ID,
+		Kind:      info.Kind,
+		Status:    info.Status,
+		StartedAt: info.StartedAt,
+		NewItems:  info.NewItems,
+		Error:     info.Error,
+		Reasons:   []string{},
+	}
+	if info.FinishedAt != nil {
+		run.DurationMS = info.FinishedAt.Sub(info.StartedAt).Milliseconds()
+	}
+
+	if stats != nil {
+		stats.mu.Lock()
+		run.ListPages = stats.ListPages
+		run.EmptyListPages = stats.EmptyListPages
+		run.ListErrors = stats.ListErrors
+		run.Rows = stats.Rows
+		run.Items = stats.Items
+		run.MissingDate = stats.MissingDate
+		run.MissingTitle = stats.MissingTitle
+		run.MissingLink = stats.MissingLink
+		run.DetailPages = stats.DetailPages
+		run.DetailErrors = stats.DetailErrors
+		run.MissingTicker = stats.MissingTicker
+		stats.mu.Unlock()
+	}
+
+	return run
+}
+
+// assess marks the run degraded when its extraction looks broken on its
+// own or next to the baseline of earlier healthy runs of the same kind
+func (r *ScrapeRun) assess(baseline []ScrapeRun) {
+	var reasons []string
+
+	if r.ListPages > 0 && r.ListErrors == r.ListPages {
+		reasons = append(reasons, "no story list page could be loaded")
+	}
+	if r.EmptyListPages > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d story list pages had no news rows", r.EmptyListPages))
+	}
+	if r.Rows > 0 {
+		for _, field := range []struct {
+			name    string
+			missing int
+		}{
+			{"date", r.MissingDate},
+			{"title", r.MissingTitle},
+			{"link", r.MissingLink},
+		} {
+			if float64(field.missing)/float64(r.Rows) >= fieldFailureRatio {
+				reasons = append(reasons, fmt.Sprintf("%s missing in %d of %d rows", field.name, field.missing, r.Rows))
+			}
+		}
+	}
+
+	// Backfill runs cover a varying number of pages, so only compare
+	// update runs with each other
+	if r.Kind == RunKindUpdate && len(baseline) > 0 {
+		rows := make([]int, len(baseline))
+		var tickerMisses, tickerPages int
+		for i, b := range baseline {
+			rows[i] = b.Rows
+			tickerMisses += b.MissingTicker
+			tickerPages += b.DetailPages - b.DetailErrors
+		}
+		sort.Ints(rows)
+		median := rows[len(rows)/2]
+		if median > 0 && float64(r.Rows) < float64(median)*rowDropRatio {
+			reasons = append(reasons, fmt.Sprintf("listed %d rows, usually %d", r.Rows, median))
+		}
+
+		pages := r.DetailPages - r.DetailErrors
+		if pages >= minTickerSample {
+			rate := float64(r.MissingTicker) / float64(pages)
+			usual := 0.0
+			if tickerPages > 0 {
+				usual = float64(tickerMisses) / float64(tickerPages)
+			}
+			if rate-usual >= tickerFailureIncrease {
+				reasons = append(reasons, fmt.Sprintf("ticker missing on %d of %d detail pages, usually %.0f%%",
+					r.MissingTicker, pages, usual*100))
+			}
+		}
+	}
+
+	r.Degraded = len(reasons) > 0
+	r.Reasons = append([]string{}, reasons...)
+}
+
+// SaveScrapeRun assesses a finished run against recent healthy runs and
+// stores its health record
+func (s *NewsStore) SaveScrapeRun(run *ScrapeRun) error {
+	baseline, err := s.healthyScrapeRuns(run.Kind, healthBaselineRuns)
+	if err != nil {
+		return err
+	}
+	run.assess(baseline)
+	if run.Degraded {
+		log.Printf("Scraper run %s is degraded: %s", run.RunID, strings.Join(run.Reasons, "; "))
+	}
+
+	res, err := s.db.Exec(`
+		INSERT INTO scrape_runs (run_id, kind, status, started_at, duration_ms,
+			list_pages, empty_list_pages, list_errors, row_count, item_count, new_items,
+			detail_pages, detail_errors, missing_date, missing_title, missing_link, missing_ticker,
+			degraded, degraded_reasons, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.RunID, run.Kind, run.Status, run.StartedAt.UTC(), run.DurationMS,
+		run.ListPages, run.EmptyListPages, run.ListErrors, run.Rows, run.Items, run.NewItems,
+		run.DetailPages, run.DetailErrors, run.MissingDate, run.MissingTitle, run.MissingLink, run.MissingTicker,
+		run.Degraded, strings.Join(run.Reasons, "\n"), run.Error)
+	if err != nil {
+		return fmt.Errorf("error saving scrape run: %w", err)
+	}
+	run.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// healthyScrapeRuns returns the latest succeeded, non-degraded runs of a kind
+func (s *NewsStore) healthyScrapeRuns(kind string, limit int) ([]ScrapeRun, error) {
+	return s.queryScrapeRuns(`WHERE kind = ? AND status = ? AND degraded = 0`, limit, kind, RunSucceeded)
+}
+
+// RecentScrapeRuns returns the latest scraper runs, newest first
+func (s *NewsStore) RecentScrapeRuns(limit int) ([]ScrapeRun, error) {
+	return s.queryScrapeRuns("", limit)
+}
+
+func (s *NewsStore) queryScrapeRuns(where string, limit int, args ...interface{}) ([]ScrapeRun, error) {
+	rows, err := s.db.Query(`
+		SELECT id, run_id, kind, status, started_at, duration_ms,
+			list_pages, empty_list_pages, list_errors, row_count, item_count, new_items,
+			detail_pages, detail_errors, missing_date, missing_title, missing_link, missing_ticker,
+			degraded, degraded_reasons, error
+		FROM scrape_runs `+where+`
+		ORDER BY id DESC
+		LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying scrape runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []ScrapeRun{}
+	for rows.Next() {
+		var r ScrapeRun
+		var reasons string
+		if err := rows.Scan(&r.ID, &r.RunID, &r.Kind, &r.Status, &r.StartedAt, &r.DurationMS,
+			&r.ListPages, &r.EmptyListPages, &r.ListErrors, &r.Rows, &r.Items, &r.NewItems,
+			&r.DetailPages, &r.DetailErrors, &r.MissingDate, &r.MissingTitle, &r.MissingLink, &r.MissingTicker,
+			&r.Degraded, &reasons, &r.Error); err != nil {
+			return nil, fmt.Errorf("error scanning scrape run: %w", err)
+		}
+		r.Reasons = []string{}
+		if reasons != "" {
+			r.Reasons = strings.Split(reasons, "\n")
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// Overall scraper health states
+const (
+	HealthUnknown  = "unknown"
+	HealthHealthy  = "healthy"
+	HealthDegraded = "degraded"
+	HealthFailing  = "failing"
+)
+
+// HealthReport summarizes the state of the scraper from its recent runs
+type HealthReport struct {
+	Status        string      `json:"status"`
+	LastRun       *ScrapeRun  `json:"last_run,omitempty"`
+	LastHealthyAt *time.Time  `json:"last_healthy_at,omitempty"`
+	RecentRuns    []ScrapeRun `json:"recent_runs"`
+}
+
+// ScraperHealth reports the scraper's state based on its latest runs
+func (s *NewsStore) ScraperHealth(limit int) (*HealthReport, error) {
+	runs, err := s.RecentScrapeRuns(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &HealthReport{Status: HealthUnknown, RecentRuns: runs}
+	if len(runs) == 0 {
+		return report, nil
+	}
+
+	report.LastRun = &runs[0]
+	switch {
+	case runs[0].Status == RunFailed:
+		report.Status = HealthFailing
+	case runs[0].Degraded:
+		report.Status = HealthDegraded
+	default:
+		report.Status = HealthHealthy
+	}
+
+	healthy, err := s.healthyScrapeRuns(RunKindUpdate, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(healthy) > 0 {
+		report.LastHealthyAt = &healthy[0].StartedAt
+	}
+
+	return report, nil
+}
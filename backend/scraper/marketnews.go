@@ -2,107 +2,170 @@ package scraper
 
 import (
 	"context"
-	"encoding/csv"
 	"fmt"
 	"io"
+	"isxportfolio-backend/arabic"
+	"isxportfolio-backend/classifier"
+	"isxportfolio-backend/registry"
 	"log"
-	"net/http"
-	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
-
-	"github.com/chromedp/cdproto/cdp"
-	"github.com/chromedp/chromedp"
 )
 
 // Attachment Datatype
 type Attachment struct {
-	URL      string
-	Filename string
-	IsLoaded bool
+	ID            int64  `json:"id"`
+	URL           string `json:"url"`
+	Filename      string `json:"filename"`
+	IsLoaded      bool   `json:"is_loaded"`
+	SHA256        string `json:"sha256,omitempty"`
+	Size          int64  `json:"size"`
+	Text          string `json:"-"` // extracted PDF text, only set when freshly extracted
+	TextExtracted bool   `json:"-"`
 }
 
 // NewsItem Datatype
 type NewsItem struct {
-	Title       string       `json:"title"`
-	Link        string       `json:"link"`
-	Date        string       `json:"date"`
-	Ticker      string       `json:"ticker"`
-	IsNew       bool         `json:"is_new"`
-	Attachments []Attachment `json:"attachments"`
-}
+	ID      int64    `json:"id"`
+	Title   string   `json:"title"`
+	Link    string   `json:"link"`
+	Date    string   `json:"date"`
+	Ticker  string   `json:"ticker"` // first of Tickers
+	Tickers []string `json:"tickers"`
+	// UnverifiedTickers are the Tickers linked from the item that are not
+	// in the company registry
+	UnverifiedTickers []string     `json:"unverified_tickers,omitempty"`
+	Category          string       `json:"category"`
+	Lang              string       `json:"lang"`
+	StoryID           string       `json:"story_id,omitempty"`
+	TwinID            int64        `json:"twin_id,omitempty"` // same story in the other language
+	TitleAr           string       `json:"title_ar"`
+	TitleEn           string       `json:"title_en"`
+	IsNew             bool         `json:"is_new"`
+	Attachments       []Attachment `json:"attachments"`
+}
+
+// Base URL of the ISX portal pages, used to resolve relative news links
+const portalURL = "http://www.isx-iq.net/isxportal/portal/"
+
+// Maximum time to spend loading a single page
+const pageTimeout = 2 * time.Minute
 
 // News URLs
 var newsURLs = []string{
 	"http://www.isx-iq.net/isxportal/portal/storyList.html?currLanguage=ar&activeTab=0",
 	"http://www.isx-iq.net/isxportal/portal/storyList.html?currLanguage=ar&activeTab=1",
+	"http://www.isx-iq.net/isxportal/portal/storyList.html?currLanguage=en&activeTab=0",
+	"http://www.isx-iq.net/isxportal/portal/storyList.html?currLanguage=en&activeTab=1",
 }
 
 // Add new type to manage the entire scraping process
 type MarketNewsScraper struct {
-	CSVPath       string
+	Store         *NewsStore
 	PDFDir        string
 	BaseURL       string
+	Fetcher       PageFetcher
+	Downloader    *Downloader
+	OnProgress    func(phase string, processed, total int)
+	Stats         *ExtractionStats // what the current run extracted, if set
+	Classifier    *classifier.Classifier
+	Registry      *registry.Registry
+	Concurrency   int           // detail pages fetched in parallel
+	RunTimeout    time.Duration // upper bound for a whole run
 	ExistingItems []NewsItem
 	NewItems      []NewsItem
 	AllItems      []NewsItem
 }
 
 // Constructor for our scraper
-func NewMarketNewsScraper(csvPath, pdfDir string) *MarketNewsScraper {
+func NewMarketNewsScraper(store *NewsStore, pdfDir string) *MarketNewsScraper {
 	return &MarketNewsScraper{
-		CSVPath: "/app/data/market_news.csv",
-		PDFDir:  "/app/data/pdfs",
+		Store:   store,
+		PDFDir:  pdfDir,
 		BaseURL: "http://www.isx-iq.net",
+		Fetcher: NewPageFetcherFromEnv(),
+
+		Downloader:  NewDownloaderFromEnv(),
+		Concurrency: envInt("SCRAPER_TABS", defaultConcurrency),
+		RunTimeout:  time.Duration(envInt("SCRAPER_RUN_TIMEOUT_MINUTES", 15)) * time.Minute,
+	}
+}
+
+// Close releases the resources held by the page fetcher
+func (s *MarketNewsScraper) Close() error {
+	if closer, ok := s.Fetcher.(io.Closer); ok {
+		return closer.Close()
 	}
+	return nil
 }
 
-// Main method to run the entire process
-func (s *MarketNewsScraper) Run() error {
+// Main method to run the entire process. The run stops when ctx is done or
+// after RunTimeout.
+func (s *MarketNewsScraper) Run(ctx context.Context) error {
 	log.Println("=== Starting Market News Scraper ===")
 
+	ctx, cancel := context.WithTimeout(ctx, s.RunTimeout)
+	defer cancel()
+
 	// Phase 1: Gathering News Items
-	if err := s.gatherNewsItems(); err != nil {
+	if err := s.gatherNewsItems(ctx); err != nil {
 		return fmt.Errorf("error gathering news items: %w", err)
 	}
 
 	// Phase 2: Processing Items
-	if err := s.processNewsItems(); err != nil {
-		return fmt.Errorf("error processing news items: %w", err)
-	}
+	_, processErr := s.processNewsItems(ctx)
 
-	// Phase 3: Save Results
-	if err := s.saveResults(); err != nil {
+	// Phase 3: Save Results, including those processed before a timeout
+	if err := s.saveResults(ctx); err != nil {
 		return fmt.Errorf("error saving results: %w", err)
 	}
 
+	if processErr != nil {
+		return fmt.Errorf("error processing news items: %w", processErr)
+	}
+
+	// Index attachments downloaded before text extraction existed
+	s.indexPendingAttachments(ctx)
+
 	log.Println("=== Market News Scraper Finished ===")
 	return nil
 }
 
+// reportProgress forwards progress to OnProgress when it is set
+func (s *MarketNewsScraper) reportProgress(phase string, processed, total int) {
+	if s.OnProgress != nil {
+		s.OnProgress(phase, processed, total)
+	}
+}
+
 // Phase 1: Gather news items
-func (s *MarketNewsScraper) gatherNewsItems() error {
+func (s *MarketNewsScraper) gatherNewsItems(ctx context.Context) error {
 	log.Println("=== Phase 1: Gathering News Items ===")
-
-	// Read existing items
-	existing, err := s.readExistingCSV()
-	if err != nil {
-		log.Printf("Error reading existing CSV: %v", err)
-		existing = []NewsItem{} // Start fresh if error
-	}
-	s.ExistingItems = existing
-	log.Printf("Read %d existing items from CSV", len(existing))
+	s.reportProgress("gathering", 0, 0)
 
 	// Get basic info for all news items
-	newItems, err := s.getNewsItemsList()
+	newItems, err := s.getNewsItemsList(ctx)
 	if err != nil {
 		return fmt.Errorf("error getting news list: %w", err)
 	}
 	log.Printf("Found %d items on website", len(newItems))
 
+	// Read the stored copies of the items we found
+	links := make([]string, len(newItems))
+	for i, item := range newItems {
+		links[i] = item.Link
+	}
+	existing, err := s.Store.FindByLinks(links)
+	if err != nil {
+		return fmt.Errorf("error reading stored news items: %w", err)
+	}
+	s.ExistingItems = existing
+	log.Printf("Read %d existing items from database", len(existing))
+
 	// Merge and identify new items
 	s.AllItems = s.mergeNewsItems(s.ExistingItems, newItems)
 
@@ -120,52 +183,109 @@ func (s *MarketNewsScraper) gatherNewsItems() error {
 }
 
 // Phase 2: Process items
-func (s *MarketNewsScraper) processNewsItems() error {
+// Details are fetched by Concurrency workers in parallel. New items whose
+// details could not be fetched, or that were not reached before the run's
+// deadline, are dropped from AllItems so the next run picks them up again.
+// It returns how many items were dropped.
+func (s *MarketNewsScraper) processNewsItems(ctx context.Context) (int, error) {
 	log.Println("=== Phase 2: Processing Individual News Items ===")
 
-	// Count new items first
-	var newItems int
-	for _, item := range s.AllItems {
+	// Collect new items first
+	var pending []int
+	for i, item := range s.AllItems {
 		if item.IsNew {
-			newItems++
+			pending = append(pending, i)
 		}
 	}
+	newItems := len(pending)
 
 	if newItems == 0 {
 		log.Println("No new items to process, skipping details retrieval")
-		return nil
+		return 0, nil
 	}
 
-	log.Printf("Processing %d new items...", newItems)
+	workers := s.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	log.Printf("Processing %d new items with %d workers...", newItems, workers)
+	s.reportProgress("processing", 0, newItems)
 
-	for i := range s.AllItems {
-		if !s.AllItems[i].IsNew {
-			continue // Skip existing items
+	var (
+		mu        sync.Mutex
+		processed int
+		failed    = make(map[int]bool)
+		wg        sync.WaitGroup
+		queue     = make(chan int)
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				item := &s.AllItems[i]
+				err := s.processNewsItem(ctx, item)
+
+				mu.Lock()
+				if err != nil {
+					log.Printf("Error processing item %s: %v", item.Title, err)
+					failed[i] = true
+				}
+				processed++
+				log.Printf("Processed new item %d/%d: %s", processed, newItems, item.Title)
+				s.reportProgress("processing", processed, newItems)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Items after the first one not dispatched are marked failed once the
+	// workers are done, so failed is only written under mu while they run
+	dispatched := 0
+dispatch:
+	for _, i := range pending {
+		select {
+		case queue <- i:
+			dispatched++
+		case <-ctx.Done():
+			break dispatch
 		}
+	}
+	close(queue)
+	wg.Wait()
 
-		log.Printf("Processing new item %d/%d: %s", i+1, newItems, s.AllItems[i].Title)
+	for _, skipped := range pending[dispatched:] {
+		failed[skipped] = true
+	}
 
-		if err := s.processNewsItem(&s.AllItems[i]); err != nil {
-			log.Printf("Error processing item: %v", err)
-			continue
+	if len(failed) > 0 {
+		log.Printf("%d new items could not be processed and will be retried next run", len(failed))
+		kept := s.AllItems[:0]
+		for i, item := range s.AllItems {
+			if !failed[i] {
+				kept = append(kept, item)
+			}
 		}
+		s.AllItems = kept
 	}
 
-	return nil
+	if err := ctx.Err(); err != nil {
+		return len(failed), fmt.Errorf("run stopped before all items were processed: %w", err)
+	}
+	return len(failed), nil
 }
 
 // Process single news item
-func (s *MarketNewsScraper) processNewsItem(item *NewsItem) error {
-	log.Printf("Processing item: %s", item.Title)
-
+func (s *MarketNewsScraper) processNewsItem(ctx context.Context, item *NewsItem) error {
 	// Get details
-	if err := s.getNewsItemDetails(item); err != nil {
+	if err := s.getNewsItemDetails(ctx, item); err != nil {
 		return fmt.Errorf("error getting details: %w", err)
 	}
 
 	// Process attachments
 	if len(item.Attachments) > 0 {
-		if err := s.processAttachments(item); err != nil {
+		if err := s.processAttachments(ctx, item); err != nil {
 			return fmt.Errorf("error processing attachments: %w", err)
 		}
 	}
@@ -174,33 +294,37 @@ func (s *MarketNewsScraper) processNewsItem(item *NewsItem) error {
 }
 
 // Phase 3: Save results
-func (s *MarketNewsScraper) saveResults() error {
+func (s *MarketNewsScraper) saveResults(ctx context.Context) error {
 	log.Println("=== Phase 3: Saving Results ===")
+	s.reportProgress("saving", len(s.AllItems), len(s.AllItems))
 
 	// Sort items
 	log.Println("Sorting items by date...")
 	s.sortNewsByDateTime()
 
-	// Save to CSV
-	if err := s.saveToCSV(); err != nil {
-		return fmt.Errorf("error saving to CSV: %w", err)
+	s.classifyItems()
+	s.tagTickers()
+
+	// Save to database
+	if err := s.Store.SaveItems(s.AllItems); err != nil {
+		return fmt.Errorf("error saving to database: %w", err)
 	}
 
 	// Verify attachments
 	log.Println("=== Verifying All Attachments ===")
-	s.verifyAllAttachments()
+	s.verifyAllAttachments(ctx)
 
 	return nil
 }
 
-// GetAllMarketNews scrapes all news from predefined URLs
-func GetAllMarketNews() ([]NewsItem, error) {
+// GetAllMarketNews scrapes all news, including details, from predefined URLs
+func GetAllMarketNews(ctx context.Context, fetcher PageFetcher) ([]NewsItem, error) {
 	log.Println("Starting GetAllMarketNews...")
 	var allNewsItems []NewsItem
 
 	for i, url := range newsURLs {
 		log.Printf("Processing URL %d of %d: %s", i+1, len(newsURLs), url)
-		newsItems, err := ScrapeMarketNews(url)
+		newsItems, err := ScrapeMarketNews(ctx, fetcher, url)
 		if err != nil {
 			log.Printf("Error scraping news from %s: %v", url, err)
 			continue
@@ -213,96 +337,23 @@ func GetAllMarketNews() ([]NewsItem, error) {
 	return allNewsItems, nil
 }
 
-// ScrapeMarketNews remains as an internal function
-func ScrapeMarketNews(url string) ([]NewsItem, error) {
+// ScrapeMarketNews gets the items of a single list page along with their details
+func ScrapeMarketNews(ctx context.Context, fetcher PageFetcher, url string) ([]NewsItem, error) {
 	log.Printf("Starting to scrape URL: %s", url)
 
-	log.Println("Setting up Edge browser options...")
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("headless", false),
-		chromedp.ExecPath("C:\\Program Files (x86)\\Microsoft\\Edge\\Application\\msedge.exe"),
-	)
-
-	log.Println("Creating allocator context...")
-	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
-	defer cancel()
-
-	log.Println("Creating browser context...")
-	ctx, cancel := chromedp.NewContext(allocCtx)
-	defer cancel()
-
-	log.Println("Starting navigation and table extraction...")
-	var rows []*cdp.Node
-	err := chromedp.Run(ctx,
-		chromedp.Navigate(url),
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			log.Println("Page loaded, waiting for table to be visible...")
-			return nil
-		}),
-		chromedp.WaitVisible(`.indnews-datarow`),
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			log.Println("News rows are visible, getting data...")
-			return nil
-		}),
-		chromedp.Nodes(`.indnews-datarow`, &rows),
-	)
+	items, err := getNewsItemsFromPage(ctx, fetcher, url, nil)
 	if err != nil {
-		log.Printf("Error during navigation/extraction: %v", err)
-		return nil, fmt.Errorf("failed to get news rows: %w", err)
+		return nil, err
 	}
-
-	log.Printf("Found %d news items", len(rows))
+	log.Printf("Found %d news items", len(items))
 
 	var newsItems []NewsItem
-	baseURL := "http://www.isx-iq.net/isxportal/portal/"
-
-	for i, row := range rows {
-		log.Printf("Processing news item %d...", i)
-
-		var item NewsItem
-		err := chromedp.Run(ctx,
-			chromedp.Text(".table-newsdata", &item.Date, chromedp.ByQuery, chromedp.FromNode(row)),
-			chromedp.Text(".indnews-title", &item.Title, chromedp.ByQuery, chromedp.FromNode(row)),
-			chromedp.AttributeValue(".indnews-title a", "href", &item.Link, nil, chromedp.ByQuery, chromedp.FromNode(row)),
-		)
-		if err != nil {
-			log.Printf("Error extracting data from row %d: %v", i, err)
-			continue
-		}
-
-		if dateEnd := strings.Index(item.Date, "\u00a0"); dateEnd != -1 {
-			item.Date = strings.TrimSpace(item.Date[:dateEnd])
-		}
-
-		log.Printf("Extracted item %d: Date=%s, Title=%s, Link=%s", i, item.Date, item.Title, item.Link)
-
-		// Get the detail page
-		detailURL := baseURL + item.Link
-		var detailHTML string
-		err = chromedp.Run(ctx,
-			chromedp.Navigate(detailURL),
-			chromedp.OuterHTML("html", &detailHTML),
-		)
-		if err != nil {
-			log.Printf("Error getting detail page %s: %v", detailURL, err)
+	for i := range items {
+		if err := GetNewsItemDetails(ctx, fetcher, &items[i]); err != nil {
+			log.Printf("Error getting details for item %d: %v", i, err)
 			continue
 		}
-
-		// Extract ticker
-		item.Ticker = ExtractTickerFromHTML(detailHTML)
-
-		// Extract PDF attachments
-		pdfLinks := ExtractPDFLinks(detailHTML)
-		for _, pdfURL := range pdfLinks {
-			filename := strings.TrimPrefix(pdfURL, "/isxportal/files/")
-			item.Attachments = append(item.Attachments, Attachment{
-				URL:      pdfURL,
-				Filename: filename,
-				IsLoaded: false, // Will be set to true after downloading
-			})
-		}
-
-		newsItems = append(newsItems, item)
+		newsItems = append(newsItems, items[i])
 	}
 
 	log.Printf("Successfully extracted %d news items", len(newsItems))
@@ -320,8 +371,8 @@ func SplitDateTime(datetime string) (date, time string) {
 
 // Add this function to parse the Iraqi date format
 func parseDateTime(dateStr string) (time.Time, error) {
-	// Format: "31/12/2024 10:14"
-	return time.Parse("02/01/2006 15:04", dateStr)
+	// Format: "31/12/2024 10:14", possibly in Arabic-Indic digits
+	return arabic.ParseDate("02/01/2006 15:04", dateStr)
 }
 
 // Add this function to sort news items
@@ -345,7 +396,12 @@ func SortNewsByDateTime(items []NewsItem) {
 
 // Add to your existing NewsItem type
 func (n NewsItem) Equals(other NewsItem) bool {
-	return n.Title == other.Title && n.Link == other.Link
+	return arabic.Normalize(n.Title) == arabic.Normalize(other.Title) && n.Link == other.Link
+}
+
+// dedupKey identifies a story independently of how its title is spelled
+func (n NewsItem) dedupKey() string {
+	return n.Lang + "|" + arabic.Normalize(n.Title) + "|" + arabic.NormalizeDigits(n.Date)
 }
 
 // MergeNewsItems combines two slices of news items and removes duplicates
@@ -375,17 +431,32 @@ func MergeNewsItems(existing, new []NewsItem) []NewsItem {
 	return merged
 }
 
+// Company profile links on a news detail page
+var companyLinkPattern = regexp.MustCompile(`(?i)companyprofilecontainer\.html\?companyCode=([A-Z0-9]+)`)
+
 // Add function to extract ticker from HTML
 func ExtractTickerFromHTML(html string) string {
-	// Look for company profile link with companyCode parameter
-	re := regexp.MustCompile(`companyprofilecontainer\.html\?companyCode=([A-Z]+)`)
-	matches := re.FindStringSubmatch(html)
-	if len(matches) > 1 {
-		return matches[1]
+	if tickers := ExtractTickersFromHTML(html); len(tickers) > 0 {
+		return tickers[0]
 	}
 	return ""
 }
 
+// ExtractTickersFromHTML returns the tickers of every company profile
+// linked from a detail page, in page order and without duplicates
+func ExtractTickersFromHTML(html string) []string {
+	var tickers []string
+	seen := make(map[string]bool)
+	for _, m := range companyLinkPattern.FindAllStringSubmatch(html, -1) {
+		ticker := strings.ToUpper(m[1])
+		if !seen[ticker] {
+			seen[ticker] = true
+			tickers = append(tickers, ticker)
+		}
+	}
+	return tickers
+}
+
 // Add function to extract PDF links
 func ExtractPDFLinks(html string) []string {
 	re := regexp.MustCompile(`/isxportal/files/story[0-9]+_[0-9_]+\.pdf`)
@@ -393,62 +464,35 @@ func ExtractPDFLinks(html string) []string {
 	return matches
 }
 
-// DownloadPDF downloads a PDF file and saves it to the specified directory
-func DownloadPDF(baseURL, pdfURL, outputDir string) error {
+// DownloadPDF downloads a PDF file into the specified directory, replacing
+// any file of the same name
+func DownloadPDF(ctx context.Context, baseURL, pdfURL, outputDir string) (*DownloadResult, error) {
 	fullURL := baseURL + pdfURL
 	log.Printf("Downloading PDF from: %s", fullURL)
 
-	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
-	}
-
-	// Get the filename from the URL
-	filename := filepath.Base(pdfURL)
-	outputPath := filepath.Join(outputDir, filename)
-
-	// Check if file already exists
-	if _, err := os.Stat(outputPath); err == nil {
-		log.Printf("PDF already exists: %s", outputPath)
-		return nil
-	}
-
-	// Download the file
-	resp, err := http.Get(fullURL)
-	if err != nil {
-		return fmt.Errorf("failed to download PDF: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s", resp.Status)
-	}
-
-	// Create the file
-	out, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
-	}
-	defer out.Close()
-
-	// Write the body to file
-	_, err = io.Copy(out, resp.Body)
+	outputPath := filepath.Join(outputDir, filepath.Base(pdfURL))
+	result, err := NewDownloaderFromEnv().Download(ctx, fullURL, outputPath)
 	if err != nil {
-		return fmt.Errorf("failed to write PDF to file: %w", err)
+		return nil, err
 	}
 
 	log.Printf("Successfully downloaded PDF to: %s", outputPath)
-	return nil
+	return result, nil
 }
 
 // GetNewsItemsList gets basic info for all news items without details
-func GetNewsItemsList() ([]NewsItem, error) {
+func GetNewsItemsList(ctx context.Context, fetcher PageFetcher) ([]NewsItem, error) {
+	return getNewsItemsList(ctx, fetcher, nil)
+}
+
+// getNewsItemsList is GetNewsItemsList that records what it extracted
+func getNewsItemsList(ctx context.Context, fetcher PageFetcher, stats *ExtractionStats) ([]NewsItem, error) {
 	log.Println("Getting list of all news items...")
 	var allNewsItems []NewsItem
 
 	for i, url := range newsURLs {
 		log.Printf("Processing URL %d of %d: %s", i+1, len(newsURLs), url)
-		newsItems, err := getNewsItemsFromPage(url)
+		newsItems, err := getNewsItemsFromPage(ctx, fetcher, url, stats)
 		if err != nil {
 			log.Printf("Error getting news from %s: %v", url, err)
 			continue
@@ -461,187 +505,51 @@ func GetNewsItemsList() ([]NewsItem, error) {
 }
 
 // getNewsItemsFromPage gets basic info from a single page
-func getNewsItemsFromPage(url string) ([]NewsItem, error) {
-	// Setup Chrome
-	ctx, cancel := setupChrome()
+func getNewsItemsFromPage(ctx context.Context, fetcher PageFetcher, url string, stats *ExtractionStats) ([]NewsItem, error) {
+	ctx, cancel := context.WithTimeout(ctx, pageTimeout)
 	defer cancel()
 
-	var rows []*cdp.Node
-	err := chromedp.Run(ctx,
-		chromedp.Navigate(url),
-		chromedp.WaitVisible(`.indnews-datarow`),
-		chromedp.Nodes(`.indnews-datarow`, &rows),
-	)
+	html, err := fetcher.Fetch(ctx, url, newsRowSelector)
 	if err != nil {
+		stats.recordListError()
 		return nil, fmt.Errorf("failed to get news rows: %w", err)
 	}
 
-	var items []NewsItem
-	for _, row := range rows {
-		var item NewsItem
-		err := chromedp.Run(ctx,
-			chromedp.Text(".table-newsdata", &item.Date, chromedp.ByQuery, chromedp.FromNode(row)),
-			chromedp.Text(".indnews-title", &item.Title, chromedp.ByQuery, chromedp.FromNode(row)),
-			chromedp.AttributeValue(".indnews-title a", "href", &item.Link, nil, chromedp.ByQuery, chromedp.FromNode(row)),
-		)
-		if err != nil {
-			continue
-		}
-
-		if dateEnd := strings.Index(item.Date, "\u00a0"); dateEnd != -1 {
-			item.Date = strings.TrimSpace(item.Date[:dateEnd])
-		}
-
-		items = append(items, item)
+	items, list, err := parseNewsList(html)
+	if err != nil {
+		stats.recordListError()
+		return nil, err
 	}
-
+	stats.recordList(list)
+	setLanguage(items, listLanguage(url))
 	return items, nil
 }
 
 // GetNewsItemDetails gets full details for a single news item
-func GetNewsItemDetails(item *NewsItem) error {
-	ctx, cancel := setupChrome()
+func GetNewsItemDetails(ctx context.Context, fetcher PageFetcher, item *NewsItem) error {
+	ctx, cancel := context.WithTimeout(ctx, pageTimeout)
 	defer cancel()
 
-	baseURL := "http://www.isx-iq.net/isxportal/portal/"
-	detailURL := baseURL + item.Link
+	detailURL := portalURL + item.Link
 
 	log.Printf("Getting details for news item: %s", item.Title)
 
-	var detailHTML string
-	err := chromedp.Run(ctx,
-		chromedp.Navigate(detailURL),
-		chromedp.OuterHTML("html", &detailHTML),
-	)
+	detailHTML, err := fetcher.Fetch(ctx, detailURL, "")
 	if err != nil {
 		return fmt.Errorf("failed to get detail page: %w", err)
 	}
 
-	// Extract ticker
-	item.Ticker = ExtractTickerFromHTML(detailHTML)
-	log.Printf("Found ticker: %s", item.Ticker)
-
-	// Extract PDF attachments
-	pdfLinks := ExtractPDFLinks(detailHTML)
-	log.Printf("Found %d PDF attachments", len(pdfLinks))
-
-	for i, pdfURL := range pdfLinks {
-		filename := strings.TrimPrefix(pdfURL, "/isxportal/files/")
-		log.Printf("Adding attachment %d/%d: %s", i+1, len(pdfLinks), filename)
-		item.Attachments = append(item.Attachments, Attachment{
-			URL:      pdfURL,
-			Filename: filename,
-			IsLoaded: false,
-		})
-	}
+	ParseNewsDetails(detailHTML, item)
+	log.Printf("Found tickers: %v", item.Tickers)
+	log.Printf("Found %d PDF attachments", len(item.Attachments))
 
 	return nil
 }
 
-// Helper function to setup Chrome
-func setupChrome() (context.Context, context.CancelFunc) {
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("headless", true),
-		chromedp.Flag("no-sandbox", true),
-		chromedp.Flag("disable-dev-shm-usage", true),
-		chromedp.Flag("disable-gpu", true),
-		chromedp.ExecPath("/usr/bin/chromium"),
-	)
-
-	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
-	ctx, cancel := chromedp.NewContext(allocCtx)
-
-	return ctx, cancel
-}
-
-// VerifyAttachments checks if all PDFs were downloaded correctly
-func VerifyAttachments(item NewsItem, pdfDir string) []string {
-	var missing []string
-
-	log.Printf("Verifying attachments for: %s", item.Title)
-	for i, att := range item.Attachments {
-		path := filepath.Join(pdfDir, att.Filename)
-		if _, err := os.Stat(path); os.IsNotExist(err) {
-			log.Printf("Warning: Attachment %d/%d missing: %s",
-				i+1, len(item.Attachments), att.Filename)
-			missing = append(missing, att.Filename)
-		} else {
-			log.Printf("Verified attachment %d/%d: %s",
-				i+1, len(item.Attachments), att.Filename)
-		}
-	}
-
-	return missing
-}
-
 // Add these methods to MarketNewsScraper
 
-func (s *MarketNewsScraper) readExistingCSV() ([]NewsItem, error) {
-	// Check if file exists
-	if _, err := os.Stat(s.CSVPath); os.IsNotExist(err) {
-		return nil, nil
-	}
-
-	file, err := os.Open(s.CSVPath)
-	if err != nil {
-		return nil, fmt.Errorf("error opening file: %w", err)
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-
-	// Skip header
-	if _, err := reader.Read(); err != nil {
-		return nil, fmt.Errorf("error reading header: %w", err)
-	}
-
-	var items []NewsItem
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("error reading CSV: %w", err)
-	}
-
-	portalURL := s.BaseURL + "/isxportal/portal/"
-	for _, record := range records {
-		if len(record) < 6 {
-			continue
-		}
-
-		link := record[3]
-		link = strings.TrimPrefix(link, portalURL)
-
-		item := NewsItem{
-			Date:   record[0] + " " + record[1],
-			Title:  record[2],
-			Link:   link,
-			Ticker: record[4],
-		}
-
-		if len(record) > 6 {
-			attachmentsStr := record[6]
-			if attachmentsStr != "" {
-				attachmentsList := strings.Split(attachmentsStr, ";")
-				for _, attStr := range attachmentsList {
-					parts := strings.Split(attStr, "|")
-					if len(parts) == 3 {
-						item.Attachments = append(item.Attachments, Attachment{
-							URL:      parts[0],
-							Filename: parts[1],
-							IsLoaded: parts[2] == "true",
-						})
-					}
-				}
-			}
-		}
-
-		items = append(items, item)
-	}
-
-	return items, nil
-}
-
-func (s *MarketNewsScraper) getNewsItemsList() ([]NewsItem, error) {
-	return GetNewsItemsList()
+func (s *MarketNewsScraper) getNewsItemsList(ctx context.Context) ([]NewsItem, error) {
+	return getNewsItemsList(ctx, s.Fetcher, s.Stats)
 }
 
 func (s *MarketNewsScraper) mergeNewsItems(existing, new []NewsItem) []NewsItem {
@@ -653,9 +561,18 @@ func (s *MarketNewsScraper) mergeNewsItems(existing, new []NewsItem) []NewsItem
 
 	var merged []NewsItem
 	var newItems []NewsItem
+	seen := make(map[string]bool)
 
 	// First check which items are actually new
 	for _, item := range new {
+		// The same story can be listed on more than one tab, sometimes
+		// with a different link or spelling
+		if seen[item.Link] || seen[item.dedupKey()] {
+			continue
+		}
+		seen[item.Link] = true
+		seen[item.dedupKey()] = true
+
 		if existingItem, exists := existingMap[item.Link]; exists {
 			// Item exists, keep the existing one with its attachments
 			item = existingItem
@@ -681,18 +598,15 @@ func (s *MarketNewsScraper) mergeNewsItems(existing, new []NewsItem) []NewsItem
 	return merged
 }
 
-func (s *MarketNewsScraper) getNewsItemDetails(item *NewsItem) error {
-	return GetNewsItemDetails(item)
+func (s *MarketNewsScraper) getNewsItemDetails(ctx context.Context, item *NewsItem) error {
+	err := GetNewsItemDetails(ctx, s.Fetcher, item)
+	s.Stats.recordDetail(*item, err)
+	return err
 }
 
-func (s *MarketNewsScraper) processAttachments(item *NewsItem) error {
+func (s *MarketNewsScraper) processAttachments(ctx context.Context, item *NewsItem) error {
 	log.Printf("Processing %d attachments for: %s", len(item.Attachments), item.Title)
 
-	// Create PDFs directory if it doesn't exist
-	if err := os.MkdirAll(s.PDFDir, 0755); err != nil {
-		return fmt.Errorf("error creating PDF directory: %w", err)
-	}
-
 	for i, att := range item.Attachments {
 		if att.IsLoaded {
 			log.Printf("Attachment %d/%d already loaded: %s",
@@ -703,16 +617,17 @@ func (s *MarketNewsScraper) processAttachments(item *NewsItem) error {
 		log.Printf("Downloading attachment %d/%d: %s",
 			i+1, len(item.Attachments), att.URL)
 
-		err := DownloadPDF(s.BaseURL, att.URL, s.PDFDir)
-		if err != nil {
+		if err := s.downloadAttachment(ctx, &item.Attachments[i]); err != nil {
 			log.Printf("Error downloading attachment %d/%d: %v",
 				i+1, len(item.Attachments), err)
 			continue
 		}
 
-		item.Attachments[i].IsLoaded = true
 		log.Printf("Successfully downloaded attachment %d/%d: %s",
 			i+1, len(item.Attachments), att.Filename)
+
+		// Extract the text for the search index
+		s.extractAttachmentText(ctx, &item.Attachments[i])
 	}
 	return nil
 }
@@ -725,65 +640,101 @@ func (s *MarketNewsScraper) sortNewsByDateTime() {
 	})
 }
 
-func (s *MarketNewsScraper) saveToCSV() error {
-	log.Printf("Writing %d items to CSV: %s", len(s.AllItems), s.CSVPath)
+// classifyItems tags every item with its disclosure category
+func (s *MarketNewsScraper) classifyItems() {
+	if s.Classifier == nil {
+		return
+	}
+	for i := range s.AllItems {
+		s.AllItems[i].Category = s.Classifier.Classify(s.AllItems[i].Title)
+	}
+}
 
-	// Create data directory if it doesn't exist
-	if err := os.MkdirAll("/app/data", 0755); err != nil {
-		return fmt.Errorf("error creating data directory: %w", err)
+// AttachmentPath returns where an attachment's file is kept
+func (s *MarketNewsScraper) AttachmentPath(att Attachment) string {
+	return filepath.Join(s.PDFDir, filepath.Base(att.Filename))
+}
+
+// FetchAttachment downloads an attachment that is not on disk yet, such as
+// when a client asks for it before the scraper got to it, and records the
+// download
+func (s *MarketNewsScraper) FetchAttachment(ctx context.Context, newsID int64, att *Attachment) error {
+	if err := s.downloadAttachment(ctx, att); err != nil {
+		return err
 	}
+	s.extractAttachmentText(ctx, att)
+	return s.Store.SaveAttachmentDownload(newsID, *att)
+}
 
-	file, err := os.Create(s.CSVPath)
+// downloadAttachment fetches an attachment into PDFDir and records its
+// checksum and size
+func (s *MarketNewsScraper) downloadAttachment(ctx context.Context, att *Attachment) error {
+	result, err := s.Downloader.Download(ctx, s.BaseURL+att.URL, s.AttachmentPath(*att))
 	if err != nil {
-		return fmt.Errorf("error creating file: %w", err)
+		return err
 	}
-	defer file.Close()
 
-	// Write UTF-8 BOM
-	file.Write([]byte{0xEF, 0xBB, 0xBF})
+	att.IsLoaded = true
+	att.SHA256 = result.SHA256
+	att.Size = result.Size
+	return nil
+}
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+// verifyAllAttachments checks the files of every listed item against their
+// checksums. Attachments that are missing, corrupt or were never downloaded
+// are downloaded again.
+func (s *MarketNewsScraper) verifyAllAttachments(ctx context.Context) {
+	var requeue []PendingAttachment
 
-	header := []string{"Date", "Time", "Description", "Link", "Ticker", "Is New", "Attachments"}
-	if err := writer.Write(header); err != nil {
-		return fmt.Errorf("error writing header: %w", err)
+	for _, item := range s.AllItems {
+		if item.ID == 0 {
+			continue
+		}
+		for _, att := range item.Attachments {
+			if !att.IsLoaded {
+				requeue = append(requeue, PendingAttachment{NewsID: item.ID, Attachment: att})
+				continue
+			}
+
+			sum, size, err := VerifyAttachmentFile(att, s.PDFDir)
+			switch {
+			case err != nil:
+				log.Printf("Attachment %s of %s failed verification: %v", att.Filename, item.Title, err)
+				requeue = append(requeue, PendingAttachment{NewsID: item.ID, Attachment: att})
+			case att.SHA256 == "":
+				// Downloaded before checksums were recorded
+				if err := s.Store.SaveAttachmentChecksum(att.ID, sum, size); err != nil {
+					log.Printf("Error saving checksum of %s: %v", att.Filename, err)
+				}
+			}
+		}
 	}
 
-	for i, item := range s.AllItems {
-		date, time := SplitDateTime(item.Date)
+	if len(requeue) == 0 {
+		log.Println("All attachments verified")
+		return
+	}
 
-		var attachmentsStr []string
-		for _, att := range item.Attachments {
-			attachmentsStr = append(attachmentsStr,
-				fmt.Sprintf("%s|%s|%v", att.URL, att.Filename, att.IsLoaded))
-		}
-		attachments := strings.Join(attachmentsStr, ";")
-
-		row := []string{
-			date,
-			time,
-			item.Title,
-			s.BaseURL + "/isxportal/portal/" + item.Link,
-			item.Ticker,
-			fmt.Sprintf("%v", item.IsNew),
-			attachments,
+	log.Printf("Downloading %d missing or corrupt attachments again", len(requeue))
+	for _, p := range requeue {
+		if ctx.Err() != nil {
+			log.Println("Run deadline reached, remaining attachments will be retried next run")
+			return
 		}
 
-		if err := writer.Write(row); err != nil {
-			log.Printf("Error writing row %d: %v", i, err)
+		if err := s.downloadAttachment(ctx, &p.Attachment); err != nil {
+			log.Printf("Error downloading %s again: %v", p.Filename, err)
+			if p.IsLoaded {
+				if err := s.Store.MarkAttachmentNotLoaded(p.ID); err != nil {
+					log.Printf("Error updating attachment %s: %v", p.Filename, err)
+				}
+			}
 			continue
 		}
-		log.Printf("Wrote item %d to CSV", i)
-	}
 
-	return nil
-}
-
-func (s *MarketNewsScraper) verifyAllAttachments() {
-	for _, item := range s.AllItems {
-		if missing := VerifyAttachments(item, s.PDFDir); len(missing) > 0 {
-			log.Printf("Missing attachments for %s: %v", item.Title, missing)
+		s.extractAttachmentText(ctx, &p.Attachment)
+		if err := s.Store.SaveAttachmentDownload(p.NewsID, p.Attachment); err != nil {
+			log.Printf("Error saving attachment %s: %v", p.Filename, err)
 		}
 	}
 }
@@ -0,0 +1,200 @@
+package scraper
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"isxportfolio-backend/arabic"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Paging limits for news queries
+const (
+	DefaultNewsLimit = 20
+	MaxNewsLimit     = 100
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// NewsFilter holds the criteria for listing stored news items
+type NewsFilter struct {
+	Ticker         string
+	Category       string
+	Lang           string    // also lists other-language items without a twin
+	From           time.Time // inclusive, zero means no lower bound
+	To             time.Time // exclusive, zero means no upper bound
+	Query          string
+	HasAttachments *bool
+	Page           int
+	Limit          int
+	Cursor         string // takes precedence over Page when set
+}
+
+// NewsPage is a single page of news query results
+type NewsPage struct {
+	Items      []NewsItem `json:"items"`
+	Total      int        `json:"total"`
+	Page       int        `json:"page"`
+	Limit      int        `json:"limit"`
+	NextPage   *int       `json:"next_page"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// Query returns the stored news items matching the filter, newest first
+func (s *NewsStore) Query(filter NewsFilter) (*NewsPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultNewsLimit
+	}
+	if filter.Limit > MaxNewsLimit {
+		filter.Limit = MaxNewsLimit
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+
+	var where []string
+	var args []interface{}
+
+	if filter.Ticker != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM news_tickers t WHERE t.news_id = n.id AND t.ticker = ?)")
+		args = append(args, strings.ToUpper(filter.Ticker))
+	}
+	if filter.Category != "" {
+		where = append(where, "n.category = ?")
+		args = append(args, filter.Category)
+	}
+	if filter.Lang != "" {
+		where = append(where, "(n.lang = ? OR n.twin_id IS NULL)")
+		args = append(args, filter.Lang)
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "n.published_at >= ?")
+		args = append(args, filter.From.Format(dbTimeLayout))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "n.published_at < ?")
+		args = append(args, filter.To.Format(dbTimeLayout))
+	}
+	if q := arabic.Normalize(filter.Query); q != "" {
+		where = append(where, "n.title_normalized LIKE ? ESCAPE '\\'")
+		args = append(args, "%"+escapeLike(q)+"%")
+	}
+	if filter.HasAttachments != nil {
+		exists := "EXISTS (SELECT 1 FROM news_attachments a WHERE a.news_id = n.id)"
+		if !*filter.HasAttachments {
+			exists = "NOT " + exists
+		}
+		where = append(where, exists)
+	}
+
+	whereSQL := ""
+	if len(where) > 0 {
+		whereSQL = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	page := &NewsPage{Page: filter.Page, Limit: filter.Limit, Items: []NewsItem{}}
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM news_items n "+whereSQL, args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("error counting news items: %w", err)
+	}
+
+	// Keyset pagination when a cursor is given, offset pagination otherwise
+	pageSQL := whereSQL
+	pageArgs := append([]interface{}{}, args...)
+	offset := (filter.Page - 1) * filter.Limit
+	if filter.Cursor != "" {
+		publishedAt, id, err := decodeNewsCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cond := "(COALESCE(n.published_at, ''), n.id) < (?, ?)"
+		if pageSQL == "" {
+			pageSQL = "WHERE " + cond
+		} else {
+			pageSQL += " AND " + cond
+		}
+		pageArgs = append(pageArgs, publishedAt, id)
+		offset = 0
+		page.Page = 0
+	}
+
+	rows, err := s.db.Query(`
+		SELECT n.id, n.title, n.link, n.date, n.ticker, n.category, n.lang, n.story_id,
+			COALESCE(n.published_at, '')
+		FROM news_items n
+		`+pageSQL+`
+		ORDER BY COALESCE(n.published_at, '') DESC, n.id DESC
+		LIMIT ? OFFSET ?`, append(pageArgs, filter.Limit+1, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying news items: %w", err)
+	}
+	defer rows.Close()
+
+	var lastPublishedAt string
+	for rows.Next() {
+		var item NewsItem
+		var publishedAt string
+		if err := rows.Scan(&item.ID, &item.Title, &item.Link, &item.Date, &item.Ticker, &item.Category,
+			&item.Lang, &item.StoryID, &publishedAt); err != nil {
+			return nil, fmt.Errorf("error scanning news item: %w", err)
+		}
+		if len(page.Items) == filter.Limit {
+			// One extra row tells us there is a next page
+			last := page.Items[len(page.Items)-1]
+			page.NextCursor = encodeNewsCursor(lastPublishedAt, last.ID)
+			if page.Page > 0 {
+				next := page.Page + 1
+				page.NextPage = &next
+			}
+			break
+		}
+		page.Items = append(page.Items, item)
+		lastPublishedAt = publishedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading news items: %w", err)
+	}
+
+	if err := s.loadAttachments(page.Items); err != nil {
+		return nil, err
+	}
+	if err := s.loadTickers(page.Items); err != nil {
+		return nil, err
+	}
+	if err := s.loadTwinTitles(page.Items); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// escapeLike escapes the LIKE wildcards in a user supplied search term
+func escapeLike(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "%", `\%`)
+	return strings.ReplaceAll(s, "_", `\_`)
+}
+
+// encodeNewsCursor builds the opaque cursor pointing after the given item
+func encodeNewsCursor(publishedAt string, id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(publishedAt + "|" + strconv.FormatInt(id, 10)))
+}
+
+// decodeNewsCursor reverses encodeNewsCursor
+func decodeNewsCursor(cursor string) (string, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+	sep := strings.LastIndex(string(raw), "|")
+	if sep == -1 {
+		return "", 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(string(raw[sep+1:]), 10, 64)
+	if err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+	return string(raw[:sep]), id, nil
+}
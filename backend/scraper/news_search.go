@@ -0,0 +1,227 @@
+package scraper
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"isxportfolio-backend/arabic"
+	"strings"
+)
+
+// ErrSearchDisabled is returned when SQLite was built without FTS5
+var ErrSearchDisabled = errors.New("full-text search is not available")
+
+// Markers placed around matched terms in highlights and snippets
+const (
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+)
+
+// Number of words of attachment text shown in a snippet
+const snippetWords = 24
+
+// SearchResult is a single full-text match. Attachment is set when the
+// match is in the text of a PDF rather than in the news title.
+type SearchResult struct {
+	News       NewsItem    `json:"news"`
+	Attachment *Attachment `json:"attachment,omitempty"`
+	Title      string      `json:"title_highlight"`
+	Snippet    string      `json:"snippet,omitempty"`
+}
+
+// PendingAttachment is a downloaded attachment whose text is not indexed yet
+type PendingAttachment struct {
+	NewsID int64
+	Attachment
+}
+
+// reindex rebuilds the search rows of one news item: one row for its title
+// and one per attachment with extracted text
+func (s *NewsStore) reindex(tx *sql.Tx, newsID int64) error {
+	if !s.searchEnabled {
+		return nil
+	}
+
+	if _, err := tx.Exec("DELETE FROM news_search WHERE news_id = ?", newsID); err != nil {
+		return fmt.Errorf("error clearing search index: %w", err)
+	}
+
+	// Index the normalized text so spelling variants match each other
+	var title string
+	if err := tx.QueryRow("SELECT title FROM news_items WHERE id = ?", newsID).Scan(&title); err != nil {
+		return fmt.Errorf("error reading news title: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO news_search (title, body, news_id, attachment_id)
+		VALUES (?, '', ?, NULL)`, arabic.Normalize(title), newsID); err != nil {
+		return fmt.Errorf("error indexing news title: %w", err)
+	}
+
+	rows, err := tx.Query("SELECT id, text FROM news_attachments WHERE news_id = ? AND text <> ''", newsID)
+	if err != nil {
+		return fmt.Errorf("error reading attachment text: %w", err)
+	}
+	type attachmentText struct {
+		id   int64
+		text string
+	}
+	var texts []attachmentText
+	for rows.Next() {
+		var t attachmentText
+		if err := rows.Scan(&t.id, &t.text); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning attachment text: %w", err)
+		}
+		texts = append(texts, t)
+	}
+	rows.Close()
+
+	for _, t := range texts {
+		if _, err := tx.Exec(`
+			INSERT INTO news_search (title, body, news_id, attachment_id)
+			VALUES ('', ?, ?, ?)`, arabic.Normalize(t.text), newsID, t.id); err != nil {
+			return fmt.Errorf("error indexing attachment text: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// AttachmentsWithoutText lists downloaded attachments whose text has not
+// been extracted yet
+func (s *NewsStore) AttachmentsWithoutText(limit int) ([]PendingAttachment, error) {
+	rows, err := s.db.Query(`
+		SELECT id, news_id, url, filename, is_loaded
+		FROM news_attachments
+		WHERE is_loaded = 1 AND text_extracted = 0
+		ORDER BY id DESC
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var pending []PendingAttachment
+	for rows.Next() {
+		var p PendingAttachment
+		if err := rows.Scan(&p.ID, &p.NewsID, &p.URL, &p.Filename, &p.IsLoaded); err != nil {
+			return nil, fmt.Errorf("error scanning attachment: %w", err)
+		}
+		pending = append(pending, p)
+	}
+
+	return pending, rows.Err()
+}
+
+// SaveAttachmentText stores the extracted text of an attachment and
+// refreshes the search index of its news item
+func (s *NewsStore) SaveAttachmentText(newsID int64, att Attachment) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE news_attachments SET text = ?, text_extracted = 1
+		WHERE id = ?`, att.Text, att.ID); err != nil {
+		return fmt.Errorf("error saving attachment text: %w", err)
+	}
+	if err := s.reindex(tx, newsID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Search runs a full-text query over news titles and attachment text,
+// best matches first
+func (s *NewsStore) Search(query string, limit int) ([]SearchResult, error) {
+	if !s.searchEnabled {
+		return nil, ErrSearchDisabled
+	}
+	if limit <= 0 {
+		limit = DefaultNewsLimit
+	}
+	if limit > MaxNewsLimit {
+		limit = MaxNewsLimit
+	}
+
+	match := ftsQuery(arabic.Normalize(query))
+	if match == "" {
+		return []SearchResult{}, nil
+	}
+
+	rows, err := s.db.Query(`
+		SELECT n.id, n.title, n.link, n.date, n.ticker, n.category, n.lang, n.story_id,
+			a.id, a.url, a.filename, a.is_loaded, a.text
+		FROM news_search
+		JOIN news_items n ON n.id = news_search.news_id
+		LEFT JOIN news_attachments a ON a.id = news_search.attachment_id
+		WHERE news_search MATCH ?
+		ORDER BY rank
+		LIMIT ?`,
+		match, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error searching news: %w", err)
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+	for rows.Next() {
+		var r SearchResult
+		var attID sql.NullInt64
+		var attURL, attFilename, attText sql.NullString
+		var attLoaded sql.NullBool
+		if err := rows.Scan(&r.News.ID, &r.News.Title, &r.News.Link, &r.News.Date, &r.News.Ticker, &r.News.Category,
+			&r.News.Lang, &r.News.StoryID, &attID, &attURL, &attFilename, &attLoaded, &attText); err != nil {
+			return nil, fmt.Errorf("error scanning search result: %w", err)
+		}
+		// The index holds normalized text, so the title and snippet are
+		// highlighted in the original text here
+		terms := strings.Fields(query)
+		if attID.Valid {
+			r.Attachment = &Attachment{
+				ID:       attID.Int64,
+				URL:      attURL.String,
+				Filename: attFilename.String,
+				IsLoaded: attLoaded.Bool,
+			}
+			r.Snippet = arabic.Snippet(attText.String, terms, highlightStart, highlightEnd, "…", snippetWords)
+		}
+		r.Title = arabic.Highlight(r.News.Title, terms, highlightStart, highlightEnd)
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading search results: %w", err)
+	}
+
+	items := make([]NewsItem, len(results))
+	for i := range results {
+		items[i] = results[i].News
+	}
+	if err := s.loadTickers(items); err != nil {
+		return nil, err
+	}
+	if err := s.loadTwinTitles(items); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].News = items[i]
+	}
+
+	return results, nil
+}
+
+// ftsQuery turns free text into an FTS5 query matching all of its words.
+// Each word is quoted so punctuation cannot be read as query syntax.
+func ftsQuery(query string) string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		word = strings.ReplaceAll(word, `"`, "")
+		if word != "" {
+			terms = append(terms, `"`+word+`"`)
+		}
+	}
+	return strings.Join(terms, " ")
+}
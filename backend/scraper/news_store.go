@@ -0,0 +1,523 @@
+package scraper
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"isxportfolio-backend/arabic"
+	"isxportfolio-backend/classifier"
+	"log"
+	"strings"
+)
+
+// Layout used for published_at so SQLite can sort and compare it as text
+const dbTimeLayout = "2006-01-02 15:04:05"
+
+// ErrAttachmentNotFound is returned when a news item has no such attachment
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+// NewsStore persists news items and their attachments in SQLite
+type NewsStore struct {
+	db            *sql.DB
+	searchEnabled bool
+}
+
+// Constructor for the news store
+func NewNewsStore(db *sql.DB) *NewsStore {
+	var name string
+	err := db.QueryRow("SELECT name FROM sqlite_master WHERE name = 'news_search'").Scan(&name)
+	if err != nil {
+		log.Println("Full-text news search disabled: news_search table missing (build with -tags sqlite_fts5)")
+	}
+	return &NewsStore{db: db, searchEnabled: err == nil}
+}
+
+// SearchEnabled reports whether the full-text index is available
+func (s *NewsStore) SearchEnabled() bool {
+	return s.searchEnabled
+}
+
+// FindByLinks returns the stored items whose link is in links
+func (s *NewsStore) FindByLinks(links []string) ([]NewsItem, error) {
+	if len(links) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, len(links))
+	for i, link := range links {
+		args[i] = link
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, title, link, date, ticker, category, lang, story_id, COALESCE(twin_id, 0)
+		FROM news_items
+		WHERE link IN (`+placeholders(len(links))+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying news items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []NewsItem
+	for rows.Next() {
+		var item NewsItem
+		if err := rows.Scan(&item.ID, &item.Title, &item.Link, &item.Date, &item.Ticker, &item.Category,
+			&item.Lang, &item.StoryID, &item.TwinID); err != nil {
+			return nil, fmt.Errorf("error scanning news item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading news items: %w", err)
+	}
+
+	if err := s.loadAttachments(items); err != nil {
+		return nil, err
+	}
+	if err := s.loadTickers(items); err != nil {
+		return nil, err
+	}
+	if err := s.loadTwinTitles(items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// ItemsAfter returns up to limit stored items with an ID above lastID,
+// oldest first, concerning ticker and in lang when they are set. When more
+// items match, the most recent ones are returned.
+func (s *NewsStore) ItemsAfter(lastID int64, ticker, lang string, limit int) ([]NewsItem, error) {
+	where := []string{"n.id > ?"}
+	args := []interface{}{lastID}
+	if ticker != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM news_tickers t WHERE t.news_id = n.id AND t.ticker = ?)")
+		args = append(args, strings.ToUpper(ticker))
+	}
+	if lang != "" {
+		where = append(where, "(n.lang = ? OR n.twin_id IS NULL)")
+		args = append(args, lang)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, title, link, date, ticker, category, lang, story_id, twin_id FROM (
+			SELECT n.id, n.title, n.link, n.date, n.ticker, n.category, n.lang, n.story_id,
+				COALESCE(n.twin_id, 0) AS twin_id
+			FROM news_items n
+			WHERE `+strings.Join(where, " AND ")+`
+			ORDER BY n.id DESC
+			LIMIT ?
+		) ORDER BY id`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying news items after %d: %w", lastID, err)
+	}
+	defer rows.Close()
+
+	var items []NewsItem
+	for rows.Next() {
+		var item NewsItem
+		if err := rows.Scan(&item.ID, &item.Title, &item.Link, &item.Date, &item.Ticker, &item.Category,
+			&item.Lang, &item.StoryID, &item.TwinID); err != nil {
+			return nil, fmt.Errorf("error scanning news item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading news items: %w", err)
+	}
+
+	if err := s.loadAttachments(items); err != nil {
+		return nil, err
+	}
+	if err := s.loadTickers(items); err != nil {
+		return nil, err
+	}
+	if err := s.loadTwinTitles(items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// SaveItems inserts or updates the given items in a single transaction.
+// Attachment load flags are never cleared by a save, so an overlapping run
+// that has not downloaded a file yet cannot undo another run's download.
+func (s *NewsStore) SaveItems(items []NewsItem) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	itemStmt, err := tx.Prepare(`
+		INSERT INTO news_items (link, title, title_normalized, date, published_at, ticker, category, lang, story_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(link) DO UPDATE SET
+			title = excluded.title,
+			title_normalized = excluded.title_normalized,
+			date = excluded.date,
+			published_at = excluded.published_at,
+			ticker = CASE WHEN excluded.ticker <> '' THEN excluded.ticker ELSE news_items.ticker END,
+			category = excluded.category,
+			lang = excluded.lang,
+			story_id = excluded.story_id,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING id
+	`)
+	if err != nil {
+		return fmt.Errorf("error preparing news statement: %w", err)
+	}
+	defer itemStmt.Close()
+
+	attStmt, err := tx.Prepare(`
+		INSERT INTO news_attachments (news_id, url, filename, is_loaded, sha256, size, text, text_extracted)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(news_id, url) DO UPDATE SET
+			filename = excluded.filename,
+			is_loaded = MAX(news_attachments.is_loaded, excluded.is_loaded),
+			sha256 = CASE WHEN excluded.sha256 <> '' THEN excluded.sha256 ELSE news_attachments.sha256 END,
+			size = CASE WHEN excluded.sha256 <> '' THEN excluded.size ELSE news_attachments.size END,
+			text = CASE WHEN excluded.text_extracted THEN excluded.text ELSE news_attachments.text END,
+			text_extracted = MAX(news_attachments.text_extracted, excluded.text_extracted)
+		RETURNING id
+	`)
+	if err != nil {
+		return fmt.Errorf("error preparing attachment statement: %w", err)
+	}
+	defer attStmt.Close()
+
+	for i := range items {
+		item := &items[i]
+
+		item.Date = arabic.NormalizeDigits(item.Date)
+		var publishedAt interface{}
+		if t, err := parseDateTime(item.Date); err == nil {
+			publishedAt = t.Format(dbTimeLayout)
+		}
+
+		item.Title = arabic.Clean(item.Title)
+		if item.Category == "" {
+			item.Category = classifier.Other
+		}
+		if item.Lang == "" {
+			item.Lang = LangArabic
+		}
+		if item.StoryID == "" {
+			item.StoryID = StoryID(item.Link)
+		}
+		if err := itemStmt.QueryRow(item.Link, item.Title, arabic.Normalize(item.Title), item.Date, publishedAt,
+			item.Ticker, item.Category, item.Lang, item.StoryID).Scan(&item.ID); err != nil {
+			return fmt.Errorf("error saving news item %s: %w", item.Link, err)
+		}
+		if err := saveTickers(tx, item); err != nil {
+			return err
+		}
+
+		for j := range item.Attachments {
+			att := &item.Attachments[j]
+			if err := attStmt.QueryRow(item.ID, att.URL, att.Filename, att.IsLoaded, att.SHA256, att.Size, att.Text, att.TextExtracted).Scan(&att.ID); err != nil {
+				return fmt.Errorf("error saving attachment %s: %w", att.URL, err)
+			}
+		}
+
+		if err := s.reindex(tx, item.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := pairTwins(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing news items: %w", err)
+	}
+
+	log.Printf("Saved %d news items to database", len(items))
+	return s.loadTwinTitles(items)
+}
+
+// pairTwins links the Arabic and English versions of each story that is
+// not paired yet, first by their ISX story ID and then by a shared
+// attachment file
+func pairTwins(tx *sql.Tx) error {
+	res, err := tx.Exec(`
+		UPDATE news_items SET twin_id = (
+			SELECT o.id FROM news_items o
+			WHERE o.story_id = news_items.story_id AND o.lang <> news_items.lang
+			ORDER BY o.id LIMIT 1)
+		WHERE twin_id IS NULL AND story_id <> '' AND EXISTS (
+			SELECT 1 FROM news_items o
+			WHERE o.story_id = news_items.story_id AND o.lang <> news_items.lang)`)
+	if err != nil {
+		return fmt.Errorf("error pairing news by story ID: %w", err)
+	}
+	byStory, _ := res.RowsAffected()
+
+	res, err = tx.Exec(`
+		UPDATE news_items SET twin_id = (
+			SELECT o.id FROM news_attachments a
+			JOIN news_attachments b ON b.filename = a.filename AND b.news_id <> a.news_id
+			JOIN news_items o ON o.id = b.news_id AND o.lang <> news_items.lang
+			WHERE a.news_id = news_items.id
+			ORDER BY o.id LIMIT 1)
+		WHERE twin_id IS NULL AND EXISTS (
+			SELECT 1 FROM news_attachments a
+			JOIN news_attachments b ON b.filename = a.filename AND b.news_id <> a.news_id
+			JOIN news_items o ON o.id = b.news_id AND o.lang <> news_items.lang
+			WHERE a.news_id = news_items.id)`)
+	if err != nil {
+		return fmt.Errorf("error pairing news by attachment: %w", err)
+	}
+	byAttachment, _ := res.RowsAffected()
+
+	if byStory+byAttachment > 0 {
+		log.Printf("Paired %d news items with their translation (%d by story ID, %d by attachment)",
+			byStory+byAttachment, byStory, byAttachment)
+	}
+	return nil
+}
+
+// loadTwinTitles fills in the twin and the Arabic and English titles of
+// the given stored items
+func (s *NewsStore) loadTwinTitles(items []NewsItem) error {
+	byID := make(map[int64]*NewsItem, len(items))
+	var args []interface{}
+	for i := range items {
+		item := &items[i]
+		item.TitleAr, item.TitleEn = "", ""
+		if item.Lang == LangEnglish {
+			item.TitleEn = item.Title
+		} else {
+			item.TitleAr = item.Title
+		}
+		if item.ID != 0 {
+			byID[item.ID] = item
+			args = append(args, item.ID)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	rows, err := s.db.Query(`
+		SELECT n.id, t.id, t.title, t.lang
+		FROM news_items n
+		JOIN news_items t ON t.id = n.twin_id
+		WHERE n.id IN (`+placeholders(len(args))+`)`, args...)
+	if err != nil {
+		return fmt.Errorf("error querying twin titles: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, twinID int64
+		var title, lang string
+		if err := rows.Scan(&id, &twinID, &title, &lang); err != nil {
+			return fmt.Errorf("error scanning twin title: %w", err)
+		}
+		item, ok := byID[id]
+		if !ok {
+			continue
+		}
+		item.TwinID = twinID
+		if lang == LangEnglish {
+			item.TitleEn = title
+		} else {
+			item.TitleAr = title
+		}
+	}
+	return rows.Err()
+}
+
+// NormalizeStoredTitles fills in title_normalized for items saved before
+// it existed and refreshes their search rows
+func (s *NewsStore) NormalizeStoredTitles() error {
+	rows, err := s.db.Query("SELECT id, title FROM news_items WHERE title_normalized = ''")
+	if err != nil {
+		return fmt.Errorf("error querying news titles: %w", err)
+	}
+
+	type pendingTitle struct {
+		id    int64
+		title string
+	}
+	var pending []pendingTitle
+	for rows.Next() {
+		var p pendingTitle
+		if err := rows.Scan(&p.id, &p.title); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning news title: %w", err)
+		}
+		pending = append(pending, p)
+	}
+	rows.Close()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, p := range pending {
+		title := arabic.Clean(p.title)
+		if _, err := tx.Exec("UPDATE news_items SET title = ?, title_normalized = ? WHERE id = ?",
+			title, arabic.Normalize(title), p.id); err != nil {
+			return fmt.Errorf("error normalizing news title: %w", err)
+		}
+		if err := s.reindex(tx, p.id); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing normalized titles: %w", err)
+	}
+	log.Printf("Normalized %d stored news titles", len(pending))
+	return nil
+}
+
+// Reclassify applies classify to every stored item and saves the
+// categories that changed, so edits to the rules reach existing news
+func (s *NewsStore) Reclassify(classify func(title string) string) error {
+	rows, err := s.db.Query("SELECT id, title, category FROM news_items")
+	if err != nil {
+		return fmt.Errorf("error querying news items: %w", err)
+	}
+
+	changed := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var title, category string
+		if err := rows.Scan(&id, &title, &category); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning news item: %w", err)
+		}
+		if c := classify(title); c != category {
+			changed[id] = c
+		}
+	}
+	rows.Close()
+	if len(changed) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for id, category := range changed {
+		if _, err := tx.Exec("UPDATE news_items SET category = ? WHERE id = ?", category, id); err != nil {
+			return fmt.Errorf("error updating category: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing categories: %w", err)
+	}
+	log.Printf("Updated the category of %d stored news items", len(changed))
+	return nil
+}
+
+// FindAttachment looks up an attachment of a news item by filename
+func (s *NewsStore) FindAttachment(newsID int64, filename string) (*Attachment, error) {
+	var att Attachment
+	err := s.db.QueryRow(`
+		SELECT id, url, filename, is_loaded, sha256, size
+		FROM news_attachments
+		WHERE news_id = ? AND filename = ?`, newsID, filename).
+		Scan(&att.ID, &att.URL, &att.Filename, &att.IsLoaded, &att.SHA256, &att.Size)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying attachment: %w", err)
+	}
+	return &att, nil
+}
+
+// SaveAttachmentChecksum records the checksum and size of a file that was
+// downloaded before they were tracked
+func (s *NewsStore) SaveAttachmentChecksum(id int64, sha256 string, size int64) error {
+	if _, err := s.db.Exec("UPDATE news_attachments SET sha256 = ?, size = ? WHERE id = ?", sha256, size, id); err != nil {
+		return fmt.Errorf("error saving attachment checksum: %w", err)
+	}
+	return nil
+}
+
+// SaveAttachmentDownload records a fresh download of an attachment,
+// replacing the checksum and text of the previous file
+func (s *NewsStore) SaveAttachmentDownload(newsID int64, att Attachment) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE news_attachments
+		SET is_loaded = 1, sha256 = ?, size = ?, text = ?, text_extracted = ?
+		WHERE id = ?`, att.SHA256, att.Size, att.Text, att.TextExtracted, att.ID); err != nil {
+		return fmt.Errorf("error saving attachment download: %w", err)
+	}
+	if err := s.reindex(tx, newsID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MarkAttachmentNotLoaded flags an attachment whose file was lost so that
+// it is downloaded again
+func (s *NewsStore) MarkAttachmentNotLoaded(id int64) error {
+	if _, err := s.db.Exec("UPDATE news_attachments SET is_loaded = 0, sha256 = '', size = 0 WHERE id = ?", id); err != nil {
+		return fmt.Errorf("error updating attachment: %w", err)
+	}
+	return nil
+}
+
+// loadAttachments fills in the attachments of the given stored items
+func (s *NewsStore) loadAttachments(items []NewsItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*NewsItem, len(items))
+	args := make([]interface{}, len(items))
+	for i := range items {
+		byID[items[i].ID] = &items[i]
+		args[i] = items[i].ID
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, news_id, url, filename, is_loaded, sha256, size
+		FROM news_attachments
+		WHERE news_id IN (`+placeholders(len(items))+`)
+		ORDER BY id`, args...)
+	if err != nil {
+		return fmt.Errorf("error querying attachments: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var newsID int64
+		var att Attachment
+		if err := rows.Scan(&att.ID, &newsID, &att.URL, &att.Filename, &att.IsLoaded, &att.SHA256, &att.Size); err != nil {
+			return fmt.Errorf("error scanning attachment: %w", err)
+		}
+		if item, ok := byID[newsID]; ok {
+			item.Attachments = append(item.Attachments, att)
+		}
+	}
+
+	return rows.Err()
+}
+
+// placeholders returns n comma separated SQL placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
@@ -0,0 +1,141 @@
+package scraper
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Maximum number of stored items replayed to a reconnecting client
+const newsStreamReplay = 500
+
+// Number of items buffered per subscriber before it is dropped as too slow
+const newsStreamBuffer = 64
+
+// NewsSubscription receives newly discovered news items. Items arrives
+// closed when the broker drops a subscriber that fell behind; the client
+// is expected to reconnect and resume from the last item it saw.
+type NewsSubscription struct {
+	Items <-chan NewsItem
+
+	ticker string
+	lang   string
+	ch     chan NewsItem
+}
+
+// matches reports whether item passes the subscription's ticker and
+// language filters. Items without a twin pass any language filter.
+func (s *NewsSubscription) matches(item NewsItem) bool {
+	if s.ticker != "" && !hasTicker(item, s.ticker) {
+		return false
+	}
+	return s.lang == "" || item.Lang == s.lang || item.TwinID == 0
+}
+
+// hasTicker reports whether item concerns the company ticker
+func hasTicker(item NewsItem, ticker string) bool {
+	if strings.EqualFold(item.Ticker, ticker) {
+		return true
+	}
+	for _, t := range item.Tickers {
+		if strings.EqualFold(t, ticker) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewsBroker fans newly discovered news items out to live subscribers.
+// Items are identified by their database ID, which only grows, so a client
+// can resume with the ID of the last item it received.
+type NewsBroker struct {
+	store *NewsStore
+
+	mu   sync.Mutex
+	subs map[*NewsSubscription]struct{}
+}
+
+// Constructor for the news broker. Reconnecting clients catch up from
+// store.
+func NewNewsBroker(store *NewsStore) *NewsBroker {
+	return &NewsBroker{
+		store: store,
+		subs:  make(map[*NewsSubscription]struct{}),
+	}
+}
+
+// Subscribe registers a subscriber for items of ticker, or of all tickers
+// when ticker is empty, in lang, or in both languages when lang is empty.
+// Stored items newer than lastID are returned as a backlog to send before
+// live items, up to newsStreamReplay of the most recent. Items saved but
+// not yet published while subscribing can be in both the backlog and the
+// live items.
+func (b *NewsBroker) Subscribe(ticker, lang string, lastID int64) (*NewsSubscription, []NewsItem, error) {
+	ch := make(chan NewsItem, newsStreamBuffer)
+	sub := &NewsSubscription{Items: ch, ticker: ticker, lang: lang, ch: ch}
+
+	// Holding the lock keeps items published during the query from being
+	// missed by both the backlog and the subscription
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []NewsItem
+	if lastID > 0 {
+		items, err := b.store.ItemsAfter(lastID, ticker, lang, newsStreamReplay)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error loading news stream backlog: %w", err)
+		}
+		backlog = items
+	}
+	b.subs[sub] = struct{}{}
+
+	return sub, backlog, nil
+}
+
+// Unsubscribe removes a subscriber. It is safe to call more than once.
+func (b *NewsBroker) Unsubscribe(sub *NewsSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Publish sends items to every subscriber whose filter they match, oldest
+// first. Subscribers whose buffer is full are dropped rather than allowed
+// to hold up the scraper.
+func (b *NewsBroker) Publish(items []NewsItem) {
+	if len(items) == 0 {
+		return
+	}
+
+	items = append([]NewsItem(nil), items...)
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		for _, item := range items {
+			if !sub.matches(item) {
+				continue
+			}
+			select {
+			case sub.ch <- item:
+			default:
+				log.Println("News stream subscriber fell behind, disconnecting it")
+				delete(b.subs, sub)
+				close(sub.ch)
+			}
+			if _, ok := b.subs[sub]; !ok {
+				break
+			}
+		}
+	}
+
+	log.Printf("Published %d new news items to %d stream subscribers", len(items), len(b.subs))
+}
@@ -0,0 +1,135 @@
+package scraper
+
+import (
+	"fmt"
+	"isxportfolio-backend/arabic"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Selector for a single row in the ISX story list
+const newsRowSelector = ".indnews-datarow"
+
+// listStats counts the rows of a story list page and the fields that could
+// not be extracted from them
+type listStats struct {
+	Rows         int
+	Items        int
+	MissingDate  int
+	MissingTitle int
+	MissingLink  int
+}
+
+// Languages of the ISX story lists
+const (
+	LangArabic  = "ar"
+	LangEnglish = "en"
+)
+
+// Story ID query parameter of a news detail link
+var storyIDPattern = regexp.MustCompile(`(?i)[?&](?:storyid|newsid|id)=(\d+)`)
+
+// StoryID returns the ISX story ID in a news link, or "" if it has none
+func StoryID(link string) string {
+	if m := storyIDPattern.FindStringSubmatch(link); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// listLanguage returns the language of a story list from its currLanguage
+// parameter. Lists without one are Arabic.
+func listLanguage(listURL string) string {
+	u, err := url.Parse(listURL)
+	if err == nil && strings.EqualFold(u.Query().Get("currLanguage"), LangEnglish) {
+		return LangEnglish
+	}
+	return LangArabic
+}
+
+// setLanguage tags the items of a story list with its language. English
+// links are given an explicit currLanguage so they are told apart from the
+// Arabic version of the same story and load the English detail page.
+func setLanguage(items []NewsItem, lang string) {
+	for i := range items {
+		items[i].Lang = lang
+		items[i].StoryID = StoryID(items[i].Link)
+		if lang != LangArabic && !strings.Contains(strings.ToLower(items[i].Link), "currlanguage=") {
+			sep := "?"
+			if strings.Contains(items[i].Link, "?") {
+				sep = "&"
+			}
+			items[i].Link += sep + "currLanguage=" + lang
+		}
+	}
+}
+
+// ParseNewsList extracts the basic news item info from a storyList.html page
+func ParseNewsList(html string) ([]NewsItem, error) {
+	items, _, err := parseNewsList(html)
+	return items, err
+}
+
+// parseNewsList is ParseNewsList that also reports extraction failures, so
+// markup changes on the ISX site show up in the scraper's health
+func parseNewsList(html string) ([]NewsItem, listStats, error) {
+	var stats listStats
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, stats, fmt.Errorf("failed to parse news list: %w", err)
+	}
+
+	var items []NewsItem
+	doc.Find(newsRowSelector).Each(func(i int, row *goquery.Selection) {
+		stats.Rows++
+
+		item := NewsItem{
+			Date:  arabic.NormalizeDigits(strings.TrimSpace(row.Find(".table-newsdata").First().Text())),
+			Title: arabic.Clean(row.Find(".indnews-title").First().Text()),
+		}
+
+		if dateEnd := strings.Index(item.Date, "\u00a0"); dateEnd != -1 {
+			item.Date = strings.TrimSpace(item.Date[:dateEnd])
+		}
+		if _, err := parseDateTime(item.Date); err != nil {
+			stats.MissingDate++
+		}
+		if item.Title == "" {
+			stats.MissingTitle++
+		}
+
+		link, ok := row.Find(".indnews-title a").First().Attr("href")
+		if !ok || strings.TrimSpace(link) == "" {
+			stats.MissingLink++
+			return
+		}
+		item.Link = strings.TrimSpace(link)
+
+		items = append(items, item)
+	})
+	stats.Items = len(items)
+
+	return items, stats, nil
+}
+
+// ParseNewsDetails fills in the tickers and attachments of a news item from
+// its detail page
+func ParseNewsDetails(html string, item *NewsItem) {
+	item.Tickers = ExtractTickersFromHTML(html)
+	item.Ticker = ""
+	if len(item.Tickers) > 0 {
+		item.Ticker = item.Tickers[0]
+	}
+
+	for _, pdfURL := range ExtractPDFLinks(html) {
+		filename := strings.TrimPrefix(pdfURL, "/isxportal/files/")
+		item.Attachments = append(item.Attachments, Attachment{
+			URL:      pdfURL,
+			Filename: filename,
+			IsLoaded: false, // Will be set to true after downloading
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readTestdata returns a saved ISX page from testdata
+func readTestdata(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("reading %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestParseNewsList(t *testing.T) {
+	items, stats, err := parseNewsList(readTestdata(t, "storyList_ar.html"))
+	if err != nil {
+		t.Fatalf("parseNewsList: %v", err)
+	}
+
+	want := []NewsItem{
+		{Date: "15/09/2024 10:14", Title: "اعلان مصرف بغداد عن توزيع ارباح نقدية", Link: "newsDetails.html?storyid=48213"},
+		{Date: "14/09/2024 13:02", Title: "ايقاف التداول على اسهم شركة بغداد للمشروبات الغازية", Link: "newsDetails.html?storyid=48190"},
+		{Date: "بدون تاريخ", Title: "جلسة تداول استثنائية", Link: "newsDetails.html?storyid=48177"},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("items = %+v, want %+v", items, want)
+	}
+
+	wantStats := listStats{Rows: 4, Items: 3, MissingDate: 1, MissingLink: 1}
+	if stats != wantStats {
+		t.Errorf("stats = %+v, want %+v", stats, wantStats)
+	}
+}
+
+func TestParseNewsListEmpty(t *testing.T) {
+	items, err := ParseNewsList("<html><body><p>Service unavailable</p></body></html>")
+	if err != nil {
+		t.Fatalf("ParseNewsList: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("got %d items from a page without rows", len(items))
+	}
+}
+
+func TestSetLanguage(t *testing.T) {
+	items, err := ParseNewsList(readTestdata(t, "storyList_ar.html"))
+	if err != nil {
+		t.Fatalf("ParseNewsList: %v", err)
+	}
+	setLanguage(items, listLanguage("http://www.isx-iq.net/isxportal/portal/storyList.html?currLanguage=en&activeTab=0"))
+
+	got := items[0]
+	if got.Lang != LangEnglish || got.StoryID != "48213" {
+		t.Errorf("lang, story ID = %q, %q, want en, 48213", got.Lang, got.StoryID)
+	}
+	if want := "newsDetails.html?storyid=48213&currLanguage=en"; got.Link != want {
+		t.Errorf("link = %q, want %q", got.Link, want)
+	}
+}
+
+func TestParseNewsDetails(t *testing.T) {
+	item := NewsItem{Ticker: "OLD"}
+	ParseNewsDetails(readTestdata(t, "newsDetails.html"), &item)
+
+	if want := []string{"BBOB", "IBSD"}; !reflect.DeepEqual(item.Tickers, want) {
+		t.Errorf("tickers = %v, want %v", item.Tickers, want)
+	}
+	if item.Ticker != "BBOB" {
+		t.Errorf("ticker = %q, want BBOB", item.Ticker)
+	}
+
+	want := []Attachment{
+		{URL: "/isxportal/files/story48213_1.pdf", Filename: "story48213_1.pdf"},
+		{URL: "/isxportal/files/story48213_2_1.pdf", Filename: "story48213_2_1.pdf"},
+	}
+	if !reflect.DeepEqual(item.Attachments, want) {
+		t.Errorf("attachments = %+v, want %+v", item.Attachments, want)
+	}
+}
+
+func TestParseNewsDetailsWithoutLinks(t *testing.T) {
+	item := NewsItem{Ticker: "OLD", Tickers: []string{"OLD"}}
+	ParseNewsDetails("<html><body><p>لا توجد مرفقات</p></body></html>", &item)
+
+	if item.Ticker != "" || len(item.Tickers) != 0 || len(item.Attachments) != 0 {
+		t.Errorf("got ticker %q, tickers %v, attachments %v from a page without links",
+			item.Ticker, item.Tickers, item.Attachments)
+	}
+}
+
+func TestStoryID(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"newsDetails.html?storyid=48213", "48213"},
+		{"newsDetails.html?currLanguage=en&StoryId=7", "7"},
+		{"newsDetails.html?newsid=12", "12"},
+		{"companyprofilecontainer.html?companyCode=BBOB", ""},
+	}
+	for _, tt := range tests {
+		if got := StoryID(tt.link); got != tt.want {
+			t.Errorf("StoryID(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Maximum time to spend extracting the text of one PDF
+const pdfTextTimeout = time.Minute
+
+// Number of previously downloaded attachments indexed per run
+const pendingTextBatch = 50
+
+// ErrNoPDFTextTool is returned when pdftotext is not installed
+var ErrNoPDFTextTool = errors.New("pdftotext not found, install poppler-utils")
+
+// ExtractPDFText returns the text content of a PDF using poppler's pdftotext
+func ExtractPDFText(ctx context.Context, path string) (string, error) {
+	bin, err := exec.LookPath("pdftotext")
+	if err != nil {
+		return "", ErrNoPDFTextTool
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pdfTextTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, "-enc", "UTF-8", "-q", path, "-")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("pdftotext failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	// Collapse the layout whitespace, the index only needs the words
+	return strings.Join(strings.Fields(stdout.String()), " "), nil
+}
+
+// extractAttachmentText fills in att.Text from its downloaded file
+func (s *MarketNewsScraper) extractAttachmentText(ctx context.Context, att *Attachment) {
+	text, err := ExtractPDFText(ctx, s.AttachmentPath(*att))
+	if err != nil {
+		log.Printf("Error extracting text from %s: %v", att.Filename, err)
+		if errors.Is(err, ErrNoPDFTextTool) {
+			return // leave it to be retried once the tool is installed
+		}
+	}
+	att.Text = text
+	att.TextExtracted = true
+}
+
+// indexPendingAttachments extracts the text of downloaded attachments that
+// have not been indexed yet, such as those downloaded by older versions
+func (s *MarketNewsScraper) indexPendingAttachments(ctx context.Context) {
+	if !s.Store.SearchEnabled() {
+		return
+	}
+
+	pending, err := s.Store.AttachmentsWithoutText(pendingTextBatch)
+	if err != nil {
+		log.Printf("Error listing attachments to index: %v", err)
+		return
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	log.Printf("Extracting text from %d previously downloaded attachments", len(pending))
+	for _, p := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+		s.extractAttachmentText(ctx, &p.Attachment)
+		if !p.TextExtracted {
+			continue
+		}
+		if err := s.Store.SaveAttachmentText(p.NewsID, p.Attachment); err != nil {
+			log.Printf("Error saving text of %s: %v", p.Filename, err)
+		}
+	}
+}
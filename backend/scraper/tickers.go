@@ -0,0 +1,199 @@
+package scraper
+
+import (
+	"database/sql"
+	"fmt"
+	"isxportfolio-backend/registry"
+	"log"
+	"strings"
+)
+
+// resolveTickers checks the tickers of an item against the registry of
+// listed companies. Tickers that are not in the registry are kept but
+// flagged as unverified, since ISX links to companies the registry may not
+// know yet; ones that are not symbols at all are dropped. When no tickers
+// are left they are inferred from company names in the title.
+func resolveTickers(reg *registry.Registry, item *NewsItem) {
+	tickers := item.Tickers
+	if len(tickers) == 0 && item.Ticker != "" {
+		tickers = []string{item.Ticker}
+	}
+
+	var resolved, unverified []string
+	seen := make(map[string]bool)
+	for _, ticker := range tickers {
+		ticker = strings.ToUpper(strings.TrimSpace(ticker))
+		if ticker == "" || seen[ticker] {
+			continue
+		}
+		seen[ticker] = true
+		if !registry.ValidSymbol(ticker) {
+			log.Printf("Ignoring invalid ticker %q on news item: %s", ticker, item.Title)
+			continue
+		}
+		if reg != nil && !reg.IsListed(ticker) {
+			log.Printf("Keeping unverified ticker %s on news item: %s", ticker, item.Title)
+			unverified = append(unverified, ticker)
+		}
+		resolved = append(resolved, ticker)
+	}
+	if len(resolved) == 0 && reg != nil {
+		resolved = reg.MatchTitle(item.Title)
+	}
+
+	item.Tickers = resolved
+	item.UnverifiedTickers = unverified
+	item.Ticker = ""
+	if len(resolved) > 0 {
+		item.Ticker = resolved[0]
+	}
+}
+
+// isUnverified reports whether ticker is one of the item's unverified
+// tickers
+func isUnverified(item *NewsItem, ticker string) bool {
+	for _, t := range item.UnverifiedTickers {
+		if t == ticker {
+			return true
+		}
+	}
+	return false
+}
+
+// tagTickers resolves the tickers of every item of the current run
+func (s *MarketNewsScraper) tagTickers() {
+	if s.Registry == nil {
+		return
+	}
+	for i := range s.AllItems {
+		resolveTickers(s.Registry, &s.AllItems[i])
+	}
+}
+
+// saveTickers replaces the stored tickers of a news item. Items without
+// tickers keep the ones already stored, like the ticker column.
+func saveTickers(tx *sql.Tx, item *NewsItem) error {
+	if len(item.Tickers) == 0 && item.Ticker != "" {
+		item.Tickers = []string{item.Ticker}
+	}
+	if len(item.Tickers) == 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec("DELETE FROM news_tickers WHERE news_id = ?", item.ID); err != nil {
+		return fmt.Errorf("error clearing news tickers: %w", err)
+	}
+	for i, ticker := range item.Tickers {
+		if _, err := tx.Exec(`
+			INSERT INTO news_tickers (news_id, ticker, position, verified) VALUES (?, ?, ?, ?)
+			ON CONFLICT(news_id, ticker) DO NOTHING`, item.ID, ticker, i, !isUnverified(item, ticker)); err != nil {
+			return fmt.Errorf("error saving news ticker %s: %w", ticker, err)
+		}
+	}
+	return nil
+}
+
+// loadTickers fills in the tickers of the given stored items
+func (s *NewsStore) loadTickers(items []NewsItem) error {
+	byID := make(map[int64]*NewsItem, len(items))
+	var args []interface{}
+	for i := range items {
+		items[i].Tickers = []string{}
+		items[i].UnverifiedTickers = nil
+		if items[i].ID != 0 {
+			byID[items[i].ID] = &items[i]
+			args = append(args, items[i].ID)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	rows, err := s.db.Query(`
+		SELECT news_id, ticker, verified FROM news_tickers
+		WHERE news_id IN (`+placeholders(len(args))+`)
+		ORDER BY news_id, position`, args...)
+	if err != nil {
+		return fmt.Errorf("error querying news tickers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var newsID int64
+		var ticker string
+		var verified bool
+		if err := rows.Scan(&newsID, &ticker, &verified); err != nil {
+			return fmt.Errorf("error scanning news ticker: %w", err)
+		}
+		if item, ok := byID[newsID]; ok {
+			item.Tickers = append(item.Tickers, ticker)
+			if !verified {
+				item.UnverifiedTickers = append(item.UnverifiedTickers, ticker)
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// RetagTickers resolves the tickers of every stored item against the
+// registry and saves the ones that changed, so items saved before tickers
+// were validated, or before a company was added, are tagged correctly and
+// unverified tickers are verified once their company is known
+func (s *NewsStore) RetagTickers(reg *registry.Registry) error {
+	rows, err := s.db.Query(`
+		SELECT n.id, n.title, n.ticker, COALESCE(group_concat(t.ticker, ','), ''),
+			COALESCE(group_concat(CASE WHEN t.verified = 0 THEN t.ticker END, ','), '')
+		FROM news_items n
+		LEFT JOIN (SELECT news_id, ticker, verified FROM news_tickers ORDER BY news_id, position) t ON t.news_id = n.id
+		GROUP BY n.id`)
+	if err != nil {
+		return fmt.Errorf("error querying news tickers: %w", err)
+	}
+
+	var changed []NewsItem
+	for rows.Next() {
+		var item NewsItem
+		var stored, storedUnverified string
+		if err := rows.Scan(&item.ID, &item.Title, &item.Ticker, &stored, &storedUnverified); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning news tickers: %w", err)
+		}
+		if stored != "" {
+			item.Tickers = strings.Split(stored, ",")
+		}
+		before := stored + "|" + storedUnverified
+		resolveTickers(reg, &item)
+		if after := strings.Join(item.Tickers, ",") + "|" + strings.Join(item.UnverifiedTickers, ","); after != before {
+			changed = append(changed, item)
+		}
+	}
+	rows.Close()
+	if len(changed) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i := range changed {
+		item := &changed[i]
+		if _, err := tx.Exec("UPDATE news_items SET ticker = ? WHERE id = ?", item.Ticker, item.ID); err != nil {
+			return fmt.Errorf("error updating ticker: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM news_tickers WHERE news_id = ?", item.ID); err != nil {
+			return fmt.Errorf("error clearing news tickers: %w", err)
+		}
+		if err := saveTickers(tx, item); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing news tickers: %w", err)
+	}
+	log.Printf("Updated the tickers of %d stored news items", len(changed))
+	return nil
+}
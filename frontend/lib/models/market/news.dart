@@ -1,27 +1,43 @@
+import '../../config/api_config.dart';
+
 class NewsItem {
   final String title;
+  final String titleAr;
+  final String titleEn;
   final String link;
   final String date;
   final String ticker;
+  final List<String> tickers;
   final List<String> attachments;
 
   NewsItem({
     required this.title,
+    this.titleAr = '',
+    this.titleEn = '',
     required this.link,
     required this.date,
     this.ticker = '',
+    this.tickers = const [],
     this.attachments = const [],
   });
 
   factory NewsItem.fromJson(Map<String, dynamic> json) {
     return NewsItem(
       title: json['title'] as String,
+      titleAr: json['title_ar'] as String? ?? '',
+      titleEn: json['title_en'] as String? ?? '',
       link: json['link'] as String,
       date: json['date'] as String,
       ticker: json['ticker'] as String? ?? '',
+      tickers: (json['tickers'] as List<dynamic>?)?.cast<String>() ?? [],
       attachments: (json['attachments'] as List<dynamic>?)
-          ?.map((e) => e['url'] as String)
+          ?.map((e) => attachmentUrl(json['id'], e['filename'] as String))
           .toList() ?? [],
     );
   }
-} 
\ No newline at end of file
+
+  // Attachments are served by our API rather than linked on the ISX site
+  static String attachmentUrl(dynamic newsId, String filename) {
+    return '${ApiConfig.baseUrl}/api/market/news/$newsId/attachments/${Uri.encodeComponent(filename)}';
+  }
+}
@@ -6,12 +6,18 @@ import '../logger_service.dart';
 class NewsService {
   final String baseUrl = 'http://localhost:8000/api';
 
-  Future<List<NewsItem>> getMarketNews() async {
+  Future<List<NewsItem>> getMarketNews({String? ticker, int page = 1, int limit = 20}) async {
     try {
-      final response = await http.get(Uri.parse('$baseUrl/market/news'));
+      final uri = Uri.parse('$baseUrl/market/news').replace(queryParameters: {
+        if (ticker != null && ticker.isNotEmpty) 'ticker': ticker,
+        'page': '$page',
+        'limit': '$limit',
+      });
+      final response = await http.get(uri);
       
       if (response.statusCode == 200) {
-        final List<dynamic> newsJson = json.decode(response.body);
+        final Map<String, dynamic> body = json.decode(response.body);
+        final List<dynamic> newsJson = body['items'] as List<dynamic>? ?? [];
         return newsJson.map((json) => NewsItem.fromJson(json)).toList();
       } else {
         throw Exception('Failed to load market news');